@@ -24,6 +24,9 @@ export interface Pagination {
     itemsPerPage: number;
     page: number;
     maxPage?: number;
+    totalItems?: number;
+    cursor?: string;
+    nextCursor?: string;
 }
 
 interface LoginRequest {
@@ -194,10 +197,14 @@ interface SearchProductResponse {
             unit: string;
             energy: number;
         }[];
-        ratings: {
-            userID: number;
-            vote: number;
-        }[];
+        votes: {
+            score: number;
+            net: number;
+            count: number;
+            upVotes: number;
+            downVotes: number;
+            userVote: number;
+        };
     }[];
     userID?: number;
     pagination?: Pagination;
@@ -231,6 +238,11 @@ export const searchProducts = async (
     }
 };
 
+export interface FieldError {
+    field: string;
+    message: string;
+}
+
 interface CreateEntryRequest {
     entry: {
         productID: number;
@@ -238,9 +250,11 @@ interface CreateEntryRequest {
         quantity: number;
         date: string;
     };
+    grams?: number;
 }
 interface CreateEntryResponse {
     error?: string;
+    fields?: FieldError[];
     entry?: {
         productID: number;
         portionID: number;
@@ -270,7 +284,7 @@ export const createEntry = async (
             return parsed;
         }
         if (parsed.error) {
-            return { error: parsed.error };
+            return { error: parsed.error, fields: parsed.fields };
         }
         return { error: "Something went wrong" };
     } catch (e) {
@@ -369,14 +383,16 @@ export const getDates = async (): Promise<GetDatesResponse> => {
 interface UpdateEntryRequest {
     id: number;
     entry: {
-        productID: number;
-        portionID: number;
-        quantity: number;
-        date: string;
+        productID?: number;
+        portionID?: number;
+        quantity?: number;
+        date?: string;
+        slot?: string;
     };
 }
 interface UpdateEntryResponse {
     error?: string;
+    fields?: FieldError[];
 }
 export const updateEntry = async (
     req: UpdateEntryRequest,
@@ -400,7 +416,7 @@ export const updateEntry = async (
             return {};
         }
         if (parsed.error) {
-            return { error: parsed.error };
+            return { error: parsed.error, fields: parsed.fields };
         }
         return { error: "Something went wrong" };
     } catch (e) {
@@ -42,23 +42,9 @@ class Row extends React.PureComponent<RowProps, RowState> {
         voteSum: 0,
     };
     componentDidMount() {
-        const voteSum = this.props.product.ratings.reduce(
-            (prev: number, curr: { userID: number; vote: number }) => {
-                return prev + curr.vote;
-            },
-            0,
-        );
-        const vote = this.getUserVote();
-        this.setState({ voteSum, vote });
+        const { net, userVote } = this.props.product.votes;
+        this.setState({ voteSum: net, vote: userVote });
     }
-    getUserVote = (): number => {
-        for (const rating of this.props.product.ratings) {
-            if (rating.userID === this.props.userID) {
-                return rating.vote;
-            }
-        }
-        return 0;
-    };
 
     onInputChange = (e: React.FormEvent<HTMLInputElement>) => {
         const newValue = e.currentTarget.value;
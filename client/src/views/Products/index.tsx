@@ -22,10 +22,14 @@ export interface Product {
     name: string;
     creator: number;
     portions: Portion[];
-    ratings: {
-        userID: number;
-        vote: number;
-    }[];
+    votes: {
+        score: number;
+        net: number;
+        count: number;
+        upVotes: number;
+        downVotes: number;
+        userVote: number;
+    };
 }
 
 export interface ProductsProps extends RouteComponentProps {
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"app/service"
+	"app/service/importer"
+	"flag"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// Imports products from local Open Food Facts or USDA FoodData Central dump, e.g.
+//
+//	go run cmd/import/main.go -format off-csv -file en.openfoodfacts.org.products.csv -creator 1
+func main() {
+	format := flag.String("format", "", "dump format: off-csv, off-jsonl or usda")
+	file := flag.String("file", "", "path to the dump file")
+	creator := flag.Int("creator", 0, "id of the account set as creator of imported products")
+	statePath := flag.String("state", "", "path to the resume state file (default: <file>.state)")
+	batch := flag.Int("batch", 500, "number of records between saved progress")
+	flag.Parse()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.DebugLevel)
+	err := run(logger, *format, *file, *creator, *statePath, *batch)
+	if err != nil {
+		logger.Error(err)
+		os.Exit(1)
+	}
+}
+
+func run(logger *logrus.Logger, format, file string, creator int, statePath string, batch int) error {
+	if file == "" || creator == 0 {
+		flag.Usage()
+		return errors.New("Both -file and -creator are required")
+	}
+	if statePath == "" {
+		statePath = file + ".state"
+	}
+	source, err := filepath.Abs(file)
+	if err != nil {
+		return errors.Wrap(err, "While resolving dump path")
+	}
+	f, err := os.Open(file)
+	if err != nil {
+		return errors.Wrap(err, "While opening dump")
+	}
+	defer f.Close()
+	var reader importer.Reader
+	switch format {
+	case "off-csv":
+		reader, err = importer.NewOFFCSVReader(f)
+	case "off-jsonl":
+		reader, err = importer.NewOFFJSONLReader(f)
+	case "usda":
+		reader, err = importer.NewUSDAReader(f)
+	default:
+		return errors.New("Unknown format, use off-csv, off-jsonl or usda")
+	}
+	if err != nil {
+		return errors.Wrap(err, "While opening dump reader")
+	}
+	db, err := service.NewDBConnection()
+	if err != nil {
+		return errors.Wrap(err, "While connecting to db")
+	}
+	defer db.Close()
+	imp := importer.Importer{
+		DB:        db,
+		Logger:    logger,
+		Creator:   creator,
+		StatePath: statePath,
+		BatchSize: batch,
+	}
+	state, err := imp.Run(reader, source)
+	if err != nil {
+		return errors.Wrap(err, "While importing")
+	}
+	logger.Infof("Done, processed %d, imported %d, skipped %d", state.Processed, state.Imported, state.Skipped)
+	return nil
+}
@@ -2,11 +2,91 @@ package main
 
 import (
 	"app/service"
+	"app/service/migrations"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
 )
 
+// Starts the server, or manages the db schema when run with the migrate subcommand, e.g.
+//
+//	./app/exec migrate status
+//	./app/exec migrate up
+//	./app/exec migrate down -steps 2
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err := migrate(os.Args[2:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
 	err := service.NewService()
 	if err != nil {
 		panic(err)
 	}
 }
+
+func migrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ExitOnError)
+	steps := flags.Int("steps", 1, "number of migrations reverted by down")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: migrate up|down|status [-steps n]")
+		flags.PrintDefaults()
+	}
+	if len(args) == 0 {
+		flags.Usage()
+		return errors.New("Missing migrate command")
+	}
+	command := args[0]
+	if command != "up" && command != "down" && command != "status" {
+		flags.Usage()
+		return errors.Errorf("Unknown migrate command %s", command)
+	}
+	flags.Parse(args[1:])
+
+	db, err := service.OpenDB()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	switch command {
+	case "up":
+		applied, err := migrations.Up(db)
+		if err != nil {
+			return err
+		}
+		for _, m := range applied {
+			fmt.Printf("Applied %s\n", m)
+		}
+		fmt.Printf("%d migrations applied\n", len(applied))
+	case "down":
+		if *steps < 1 {
+			return errors.New("Steps must be at least 1")
+		}
+		reverted, err := migrations.Down(db, *steps)
+		if err != nil {
+			return err
+		}
+		for _, m := range reverted {
+			fmt.Printf("Reverted %s\n", m)
+		}
+		fmt.Printf("%d migrations reverted\n", len(reverted))
+	case "status":
+		statuses, err := migrations.GetStatus(db)
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			if s.AppliedAt == nil {
+				fmt.Printf("%-28s pending\n", s.Migration)
+			} else {
+				fmt.Printf("%-28s applied %s\n", s.Migration, s.AppliedAt.Format("2006-01-02 15:04:05"))
+			}
+		}
+	}
+	return nil
+}
@@ -4,7 +4,7 @@ import (
 	"app/service/auth"
 	"app/service/middleware"
 	"app/service/models"
-	"database/sql"
+	"app/service/store"
 	"encoding/json"
 	"log"
 	"net/http"
@@ -34,7 +34,7 @@ func (cred *Credentials) Validate() error {
 	return nil
 }
 
-func CreateAccount(db *sql.DB, logger *logrus.Logger) http.Handler {
+func CreateAccount(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidDataMsg = "Invalid request body"
 	const InvalidCredentials = "Invalid email or password"
 	const InternalError = "Internal Error"
@@ -85,7 +85,7 @@ func CreateAccount(db *sql.DB, logger *logrus.Logger) http.Handler {
 			return
 		}
 		acc := &models.Account{Email: cred.Email, Password: string(hashedBytes)}
-		count, err := models.GetAccountsCount(db)
+		count, err := stores.Accounts.GetAccountsCount()
 		if err != nil {
 			err := errors.Wrap(err, "While fetching account count")
 			sendError(w, http.StatusBadRequest, err, InternalError)
@@ -97,7 +97,7 @@ func CreateAccount(db *sql.DB, logger *logrus.Logger) http.Handler {
 		} else {
 			acc.AccessLevel = auth.User
 		}
-		err = models.CreateAccount(db, acc)
+		err = stores.Accounts.CreateAccount(acc)
 		if err != nil {
 			if pgerr, ok := err.(*pq.Error); ok {
 				if pgerr.Code == "23505" {
@@ -109,7 +109,7 @@ func CreateAccount(db *sql.DB, logger *logrus.Logger) http.Handler {
 			sendError(w, http.StatusBadRequest, err, InternalError)
 			return
 		}
-		acc, err = models.GetAccountByEmail(db, acc.Email)
+		acc, err = stores.Accounts.GetAccountByEmail(acc.Email)
 		if err != nil {
 			err = errors.Wrap(err, "While fetching account by email")
 			sendError(w, http.StatusBadRequest, err, InternalError)
@@ -137,7 +137,7 @@ func CreateAccount(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func MailChangePasswordLink(db *sql.DB, logger *logrus.Logger) http.Handler {
+func MailChangePasswordLink(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidDataMsg = "Invalid request body"
 	const NotExists = "User with that mail do not exists"
 	const AlreadySent = "Password change email was already sent"
@@ -174,7 +174,7 @@ func MailChangePasswordLink(db *sql.DB, logger *logrus.Logger) http.Handler {
 			return
 		}
 
-		acc, err := models.GetAccountByEmail(db, in.Email)
+		acc, err := stores.Accounts.GetAccountByEmail(in.Email)
 		if err != nil {
 			err = errors.Wrap(err, "While fetching account by email")
 			sendError(w, http.StatusBadRequest, err, NotExists)
@@ -185,7 +185,7 @@ func MailChangePasswordLink(db *sql.DB, logger *logrus.Logger) http.Handler {
 			sendError(w, http.StatusBadRequest, err, AlreadySent)
 			return
 		}
-		err = models.ChangePasswordRequest(db, in.Email)
+		err = stores.Accounts.ChangePasswordRequest(in.Email)
 		if err != nil {
 			err = errors.Wrap(err, "While change password flag set")
 			sendError(w, http.StatusBadRequest, err, NotExists)
@@ -214,7 +214,7 @@ func MailChangePasswordLink(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func ChangePassword(db *sql.DB, logger *logrus.Logger) http.Handler {
+func ChangePassword(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidDataMsg = "Invalid request body"
 	const InvalidCredentials = "Invalid email or password"
 	const InternalError = "Internal Error"
@@ -277,7 +277,7 @@ func ChangePassword(db *sql.DB, logger *logrus.Logger) http.Handler {
 			sendError(w, http.StatusBadRequest, err, InternalError)
 			return
 		}
-		err = models.ChangePassword(db, cred.Email, string(hashedBytes))
+		err = stores.Accounts.ChangePassword(cred.Email, string(hashedBytes))
 		if err != nil {
 			err = errors.Wrap(err, "While changing password")
 			sendError(w, http.StatusBadRequest, err, InternalError)
@@ -288,7 +288,7 @@ func ChangePassword(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func Verify(db *sql.DB, logger *logrus.Logger) http.Handler {
+func Verify(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InternalError = "Internal Error"
 	const InvalidToken = "Invalid verification token"
 	type RequestObject struct {
@@ -340,7 +340,7 @@ func Verify(db *sql.DB, logger *logrus.Logger) http.Handler {
 			return
 		}
 		acc := models.Account{ID: tokenObj.UserID, Email: tokenObj.Email, AccessLevel: tokenObj.AccessLevel}
-		err = models.VerifyAccount(db, &acc)
+		err = stores.Accounts.VerifyAccount(&acc)
 		if err != nil {
 			err = errors.Wrap(err, "While parsing token - not valid verify token")
 			sendError(w, http.StatusBadRequest, err, InternalError)
@@ -351,7 +351,7 @@ func Verify(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func Authenticate(db *sql.DB, logger *logrus.Logger) http.Handler {
+func Authenticate(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
 	const InvalidCredentials = "Invalid email or password"
 	const NotExists = "User with provided email not exists"
@@ -400,7 +400,7 @@ func Authenticate(db *sql.DB, logger *logrus.Logger) http.Handler {
 		}
 		password := cred.Password
 		email := cred.Email
-		acc, err := models.GetAccountByEmail(db, email)
+		acc, err := stores.Accounts.GetAccountByEmail(email)
 		if err != nil {
 			err = errors.Wrap(err, "While fetching account by email")
 			sendError(w, http.StatusBadRequest, err, NotExists)
@@ -440,7 +440,7 @@ func Authenticate(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func CheckIfAuthenticated(db *sql.DB, logger *logrus.Logger) http.Handler {
+func CheckIfAuthenticated(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
 	const NotAuthenticated = "You are not authenticated"
 	const InternalError = "Internal Error"
@@ -498,7 +498,7 @@ func CheckIfAuthenticated(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func BanUser(db *sql.DB, logger *logrus.Logger) http.Handler {
+func BanUser(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
 	const InternalError = "Internal Error"
 	const CannotBanYourself = "Cannot ban yourself"
@@ -540,13 +540,13 @@ func BanUser(db *sql.DB, logger *logrus.Logger) http.Handler {
 			return
 		}
 		if in.ID != userID {
-			user, err := models.GetAccountById(db, in.ID)
+			user, err := stores.Accounts.GetAccountById(in.ID)
 			if err != nil {
 				err = errors.Wrap(err, "While fetching user")
 				sendError(w, http.StatusBadRequest, err, InternalError)
 				return
 			}
-			admin, err := models.GetAccountById(db, userID)
+			admin, err := stores.Accounts.GetAccountById(userID)
 			if err != nil {
 				err = errors.Wrap(err, "While fetching user")
 				sendError(w, http.StatusBadRequest, err, InternalError)
@@ -557,7 +557,7 @@ func BanUser(db *sql.DB, logger *logrus.Logger) http.Handler {
 				sendError(w, http.StatusBadRequest, err, HaveNoRight)
 				return
 			}
-			err = models.SetAccessLevel(db, in.ID, auth.Banned)
+			err = stores.Accounts.SetAccessLevel(in.ID, auth.Banned)
 			if err != nil {
 				err = errors.Wrap(err, "While setting access level")
 				sendError(w, http.StatusBadRequest, err, InternalError)
@@ -572,7 +572,7 @@ func BanUser(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func UnbanUser(db *sql.DB, logger *logrus.Logger) http.Handler {
+func UnbanUser(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
 	const InternalError = "Internal Error"
 	const CannotBanYourself = "Cannot ban yourself"
@@ -614,13 +614,13 @@ func UnbanUser(db *sql.DB, logger *logrus.Logger) http.Handler {
 			return
 		}
 		if in.ID != userID {
-			user, err := models.GetAccountById(db, in.ID)
+			user, err := stores.Accounts.GetAccountById(in.ID)
 			if err != nil {
 				err = errors.Wrap(err, "While fetching user")
 				sendError(w, http.StatusBadRequest, err, InternalError)
 				return
 			}
-			admin, err := models.GetAccountById(db, userID)
+			admin, err := stores.Accounts.GetAccountById(userID)
 			if err != nil {
 				err = errors.Wrap(err, "While fetching user")
 				sendError(w, http.StatusBadRequest, err, InternalError)
@@ -631,7 +631,7 @@ func UnbanUser(db *sql.DB, logger *logrus.Logger) http.Handler {
 				sendError(w, http.StatusBadRequest, err, HaveNoRight)
 				return
 			}
-			err = models.SetAccessLevel(db, in.ID, auth.User)
+			err = stores.Accounts.SetAccessLevel(in.ID, auth.User)
 			if err != nil {
 				err = errors.Wrap(err, "While setting access level")
 				sendError(w, http.StatusBadRequest, err, InternalError)
@@ -646,7 +646,7 @@ func UnbanUser(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func SetAccessLevel(db *sql.DB, logger *logrus.Logger) http.Handler {
+func SetAccessLevel(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
 	const InternalError = "Internal Error"
 	const CannotSetPriveledgesYourself = "Cannot set access level for yourself"
@@ -695,13 +695,13 @@ func SetAccessLevel(db *sql.DB, logger *logrus.Logger) http.Handler {
 			return
 		}
 		if in.ID != userID {
-			user, err := models.GetAccountById(db, in.ID)
+			user, err := stores.Accounts.GetAccountById(in.ID)
 			if err != nil {
 				err = errors.Wrap(err, "While fetching user")
 				sendError(w, http.StatusBadRequest, err, InternalError)
 				return
 			}
-			admin, err := models.GetAccountById(db, userID)
+			admin, err := stores.Accounts.GetAccountById(userID)
 			if err != nil {
 				err = errors.Wrap(err, "While fetching user")
 				sendError(w, http.StatusBadRequest, err, InternalError)
@@ -712,7 +712,7 @@ func SetAccessLevel(db *sql.DB, logger *logrus.Logger) http.Handler {
 				sendError(w, http.StatusBadRequest, err, HaveNoRight)
 				return
 			}
-			err = models.SetAccessLevel(db, in.ID, auth.User)
+			err = stores.Accounts.SetAccessLevel(in.ID, auth.User)
 			if err != nil {
 				err = errors.Wrap(err, "While setting access level")
 				sendError(w, http.StatusBadRequest, err, InternalError)
@@ -727,7 +727,7 @@ func SetAccessLevel(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func SearchUsers(db *sql.DB, logger *logrus.Logger) http.Handler {
+func SearchUsers(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
 	const InternalError = "Internal Error"
 	type User struct {
@@ -772,7 +772,7 @@ func SearchUsers(db *sql.DB, logger *logrus.Logger) http.Handler {
 			sendError(w, http.StatusBadRequest, err, InvalidData)
 			return
 		}
-		accounts, pagination, err := models.SearchAccounts(db, in.Email, in.Pagination)
+		accounts, pagination, err := stores.Accounts.SearchAccounts(in.Email, in.Pagination)
 		if err != nil {
 			err = errors.Wrap(err, "While fetching users")
 			sendError(w, http.StatusBadRequest, err, InternalError)
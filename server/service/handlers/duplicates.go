@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"app/service/middleware"
+	"app/service/models"
+	"app/service/store"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func FindDuplicates(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InvalidSimilarity = "Similarity has to be between 0.3 and 1"
+	const InternalError = "Internal error"
+	const DefaultSimilarity = 0.5
+	const DefaultLimit = 20
+	const MaxLimit = 100
+
+	type Product struct {
+		*models.Product
+		Portions []models.Portion `json:"portions"`
+	}
+	type Cluster struct {
+		models.DuplicateCluster
+		Products []Product `json:"products"`
+	}
+	type RequestObject struct {
+		Similarity float64 `json:"similarity,omitempty"`
+		Limit      int     `json:"limit,omitempty"`
+	}
+	type ResponseObject struct {
+		Error    string    `json:"error,omitempty"`
+		Clusters []Cluster `json:"clusters,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While finding duplicates")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, clusters []Cluster) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Clusters: clusters,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		similarity := in.Similarity
+		if similarity == 0 {
+			similarity = DefaultSimilarity
+		}
+		if similarity < models.MinDuplicateSimilarity || similarity > 1 {
+			err = errors.New("Similarity out of range")
+			sendError(w, http.StatusBadRequest, err, InvalidSimilarity)
+			return
+		}
+		limit := in.Limit
+		if limit <= 0 || limit > MaxLimit {
+			limit = DefaultLimit
+		}
+		clusters, err := stores.Duplicates.FindDuplicateClusters(similarity, limit)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db duplicates")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		bundled := []Cluster{}
+		for _, cluster := range clusters {
+			products := []Product{}
+			for _, id := range cluster.ProductIDs {
+				product, err := stores.Products.GetProductById(id)
+				if err != nil {
+					err = errors.Wrap(err, "While getting db product")
+					sendError(w, http.StatusBadRequest, err, InternalError)
+					return
+				}
+				portions, err := stores.Portions.GetProductsPortions(id)
+				if err != nil {
+					err = errors.Wrap(err, "While getting db product portions")
+					sendError(w, http.StatusBadRequest, err, InternalError)
+					return
+				}
+				products = append(products, Product{Product: product, Portions: portions})
+			}
+			bundled = append(bundled, Cluster{DuplicateCluster: cluster, Products: products})
+		}
+		sendData(w, http.StatusOK, bundled)
+		return
+	})
+}
+
+func MergeProducts(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const NoDuplicates = "Choose at least one product to merge"
+	const SurvivorInDuplicates = "Surviving product can't be merged into itself"
+	const NotFound = "Product does not exist"
+	const RecipeMerge = "Recipes can't be merged into another product"
+	const RecipeSurvivor = "Products can't be merged into a recipe"
+	const DeletedSurvivor = "Products can't be merged into a deleted product"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+		SurvivorID   int   `json:"survivorID"`
+		DuplicateIDs []int `json:"duplicateIDs"`
+	}
+	type ResponseObject struct {
+		Error    string           `json:"error,omitempty"`
+		Revision *models.Revision `json:"revision,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While merging products")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, revision *models.Revision) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Revision: revision,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		duplicateIDs := []int{}
+		seen := map[int]bool{}
+		for _, id := range in.DuplicateIDs {
+			if id == in.SurvivorID {
+				err = errors.New(SurvivorInDuplicates)
+				sendError(w, http.StatusBadRequest, err, SurvivorInDuplicates)
+				return
+			}
+			if !seen[id] {
+				seen[id] = true
+				duplicateIDs = append(duplicateIDs, id)
+			}
+		}
+		if len(duplicateIDs) == 0 {
+			err = errors.New(NoDuplicates)
+			sendError(w, http.StatusBadRequest, err, NoDuplicates)
+			return
+		}
+		survivor, err := stores.Products.GetProductById(in.SurvivorID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db survivor")
+			sendError(w, http.StatusBadRequest, err, NotFound)
+			return
+		}
+		if survivor.Recipe {
+			err = errors.New(RecipeSurvivor)
+			sendError(w, http.StatusBadRequest, err, RecipeSurvivor)
+			return
+		}
+		if survivor.Deleted {
+			err = errors.New(DeletedSurvivor)
+			sendError(w, http.StatusBadRequest, err, DeletedSurvivor)
+			return
+		}
+		for _, id := range duplicateIDs {
+			duplicate, err := stores.Products.GetProductById(id)
+			if err != nil {
+				err = errors.Wrap(err, "While getting db duplicate")
+				sendError(w, http.StatusBadRequest, err, NotFound)
+				return
+			}
+			if duplicate.Recipe {
+				err = errors.New(RecipeMerge)
+				sendError(w, http.StatusBadRequest, err, RecipeMerge)
+				return
+			}
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		revision, err := stores.Duplicates.MergeProducts(in.SurvivorID, duplicateIDs, userID)
+		if err != nil {
+			err = errors.Wrap(err, "While merging db products")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, revision)
+		return
+	})
+}
@@ -3,7 +3,7 @@ package handlers
 import (
 	"app/service/middleware"
 	"app/service/models"
-	"database/sql"
+	"app/service/store"
 	"encoding/json"
 	"net/http"
 	"time"
@@ -12,15 +12,20 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func CreateEntry(db *sql.DB, logger *logrus.Logger) http.Handler {
+func CreateEntry(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
+	const InvalidEntry = "Invalid entry"
+	const InvalidGrams = "Product has no portion measured in grams"
 	const InternalError = "Internal error"
+	// Grams, when given, are logged as quantity of the entry portion, or of the first gram portion of the product
 	type RequestObject struct {
 		Entry *models.Entry `json:"entry"`
+		Grams float64       `json:"grams,omitempty"`
 	}
 	type ResponseObject struct {
-		Error string        `json:"error,omitempty"`
-		Entry *models.Entry `json:"entry,omitempty"`
+		Error  string              `json:"error,omitempty"`
+		Fields []models.FieldError `json:"fields,omitempty"`
+		Entry  *models.Entry       `json:"entry,omitempty"`
 	}
 	sendError := func(w http.ResponseWriter, status int, err error, message string) {
 		err = errors.Wrap(err, "While creating entry")
@@ -30,6 +35,9 @@ func CreateEntry(db *sql.DB, logger *logrus.Logger) http.Handler {
 		out := ResponseObject{
 			Error: message,
 		}
+		if invalid, ok := errors.Cause(err).(*models.ValidationError); ok {
+			out.Fields = invalid.Fields
+		}
 		json.NewEncoder(w).Encode(out)
 	}
 	sendData := func(w http.ResponseWriter, status int, entry *models.Entry) {
@@ -61,7 +69,55 @@ func CreateEntry(db *sql.DB, logger *logrus.Logger) http.Handler {
 			return
 		}
 		entry.UserID = userID
-		dbEntry, err := models.CreateEntry(db, entry)
+		entry.Slot = models.NormalizeSlot(entry.Slot)
+		slots, err := stores.Entries.GetUsersSlots(userID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db meal slots")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		if in.Grams > 0 {
+			portions, err := stores.Portions.GetProductsPortions(entry.ProductID)
+			if err != nil {
+				err = errors.Wrap(err, "While getting db portions")
+				sendError(w, http.StatusBadRequest, err, InternalError)
+				return
+			}
+			portion, err := models.PortionForAmount(portions, entry.PortionID, models.BaseGram)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, err, InvalidGrams)
+				return
+			}
+			entry.PortionID = portion.ID
+			entry.Quantity, err = models.QuantityOfAmount(*portion, in.Grams, models.BaseGram)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, err, InvalidGrams)
+				return
+			}
+		}
+		// missing product or portion is reported by validation
+		product, err := stores.Products.GetProductById(entry.ProductID)
+		if err != nil && errors.Cause(err) != models.ErrNotFound {
+			err = errors.Wrap(err, "While getting db product")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		portion, err := stores.Portions.GetPortion(entry.PortionID)
+		if err != nil && errors.Cause(err) != models.ErrNotFound {
+			err = errors.Wrap(err, "While getting db portion")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		err = models.ValidateEntry(*entry, product, portion, slots, time.Now())
+		if err != nil {
+			if _, ok := err.(*models.ValidationError); ok {
+				sendError(w, http.StatusBadRequest, err, InvalidEntry)
+				return
+			}
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		dbEntry, err := stores.Entries.CreateEntry(entry)
 		if err != nil {
 			err = errors.Wrap(err, "While creating db entry")
 			sendError(w, http.StatusBadRequest, err, InternalError)
@@ -72,9 +128,13 @@ func CreateEntry(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func GetUsersEntries(db *sql.DB, logger *logrus.Logger) http.Handler {
+// GetUsersEntries returns entries of a day, or of the days from date to the optional to date, grouped into
+// meal slots together with nutrition totals of every day. Budget of the whole response is set for a single day only.
+func GetUsersEntries(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
+	const InvalidRange = "Invalid date range"
 	const InternalError = "Internal error"
+	const maxDays = 31
 
 	type Product struct {
 		models.Product
@@ -82,15 +142,31 @@ func GetUsersEntries(db *sql.DB, logger *logrus.Logger) http.Handler {
 	}
 	type Entry struct {
 		models.Entry
-		Product Product `json:"product,omitempty"`
+		Product   Product          `json:"product,omitempty"`
+		Nutrition models.Nutrition `json:"nutrition"`
+	}
+	type Slot struct {
+		Name    string           `json:"name"`
+		Entries []Entry          `json:"entries"`
+		Total   models.Nutrition `json:"total"`
+	}
+	type Day struct {
+		Date   time.Time        `json:"date"`
+		Total  models.Nutrition `json:"total"`
+		Budget models.Budget    `json:"budget"`
 	}
 	type RequestObject struct {
 		Date       time.Time          `json:"date,omitempty"`
+		To         time.Time          `json:"to,omitempty"`
 		Pagination *models.Pagination `json:"pagination,omitempty"`
 	}
 	type ResponseObject struct {
 		Error      string             `json:"error,omitempty"`
 		Entries    *[]Entry           `json:"entries,omitempty"`
+		Slots      *[]Slot            `json:"slots,omitempty"`
+		Total      *models.Nutrition  `json:"total,omitempty"`
+		Budget     *models.Budget     `json:"budget,omitempty"`
+		Days       *[]Day             `json:"days,omitempty"`
 		Pagination *models.Pagination `json:"pagination,omitempty"`
 	}
 	sendError := func(w http.ResponseWriter, status int, err error, message string) {
@@ -103,11 +179,15 @@ func GetUsersEntries(db *sql.DB, logger *logrus.Logger) http.Handler {
 		}
 		json.NewEncoder(w).Encode(out)
 	}
-	sendData := func(w http.ResponseWriter, status int, entries *[]Entry, pagination *models.Pagination) {
+	sendData := func(w http.ResponseWriter, status int, entries *[]Entry, slots *[]Slot, total *models.Nutrition, budget *models.Budget, days *[]Day, pagination *models.Pagination) {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(status)
 		out := ResponseObject{
 			Entries:    entries,
+			Slots:      slots,
+			Total:      total,
+			Budget:     budget,
+			Days:       days,
 			Pagination: pagination,
 		}
 		json.NewEncoder(w).Encode(out)
@@ -128,7 +208,17 @@ func GetUsersEntries(db *sql.DB, logger *logrus.Logger) http.Handler {
 			return
 
 		}
-		entries, pagination, err := models.GetUsersEntries(db, userID, in.Date, in.Pagination)
+		from := models.Day(in.Date)
+		to := from
+		if !in.To.IsZero() {
+			to = models.Day(in.To)
+		}
+		if to.Before(from) || to.After(from.AddDate(0, 0, maxDays-1)) {
+			err = errors.Errorf("Range has to end after it starts and span at most %d days", maxDays)
+			sendError(w, http.StatusBadRequest, err, InvalidRange)
+			return
+		}
+		entries, pagination, err := stores.Entries.GetUsersEntries(userID, from, to, in.Pagination)
 		if err != nil {
 			err = errors.Wrap(err, "While getting db users entries")
 			sendError(w, http.StatusBadRequest, err, InternalError)
@@ -137,13 +227,13 @@ func GetUsersEntries(db *sql.DB, logger *logrus.Logger) http.Handler {
 		popEntries := []Entry{}
 		for _, entry := range *entries {
 			productID := entry.ProductID
-			product, err := models.GetProductById(db, productID)
+			product, err := stores.Products.GetProductById(productID)
 			if err != nil {
 				err = errors.Wrap(err, "While getting db product")
 				sendError(w, http.StatusBadRequest, err, InternalError)
 				return
 			}
-			portions, err := models.GetProductsPortions(db, productID)
+			portions, err := stores.Portions.GetProductsPortions(productID)
 			if err != nil {
 				err = errors.Wrap(err, "While getting db product portion")
 				sendError(w, http.StatusBadRequest, err, InternalError)
@@ -153,23 +243,92 @@ func GetUsersEntries(db *sql.DB, logger *logrus.Logger) http.Handler {
 				Product:  *product,
 				Portions: portions,
 			}
+			nutrition := models.Nutrition{}
+			for _, portion := range portions {
+				if portion.ID == entry.PortionID {
+					nutrition = portion.Nutrition.Scale(entry.Quantity)
+				}
+			}
 			popEntry := Entry{
-				Entry:   entry,
-				Product: populated,
+				Entry:     entry,
+				Product:   populated,
+				Nutrition: nutrition,
 			}
 			popEntries = append(popEntries, popEntry)
 		}
-		sendData(w, http.StatusOK, &popEntries, pagination)
+		goals, err := stores.Goals.GetUsersGoals(userID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db goals")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		// page can hold only part of the range, totals are counted over all of its entries
+		summaries, err := stores.Entries.GetUsersDailySummaries(userID, from, to)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db daily summary")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		totals := map[time.Time]models.Nutrition{}
+		for _, summary := range summaries {
+			totals[models.Day(summary.Date)] = summary.Total
+		}
+		days := []Day{}
+		total := models.Nutrition{}
+		for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+			dayTotal := totals[day]
+			total = total.Add(dayTotal)
+			days = append(days, Day{
+				Date:   day,
+				Total:  dayTotal,
+				Budget: models.NewBudget(models.GoalForDate(goals, day), dayTotal.Energy),
+			})
+		}
+		var budget *models.Budget
+		if from.Equal(to) {
+			budget = &days[0].Budget
+		}
+		slotNames, err := stores.Entries.GetUsersSlots(userID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db meal slots")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		slots := []Slot{}
+		slotIndex := map[string]int{}
+		for _, name := range slotNames {
+			slotIndex[name] = len(slots)
+			slots = append(slots, Slot{Name: name, Entries: []Entry{}})
+		}
+		for _, entry := range popEntries {
+			if _, ok := slotIndex[entry.Slot]; !ok && entry.Slot != "" {
+				slotIndex[entry.Slot] = len(slots)
+				slots = append(slots, Slot{Name: entry.Slot, Entries: []Entry{}})
+			}
+		}
+		slotIndex[""] = len(slots)
+		slots = append(slots, Slot{Name: "", Entries: []Entry{}})
+		for _, entry := range popEntries {
+			slot := &slots[slotIndex[entry.Slot]]
+			slot.Entries = append(slot.Entries, entry)
+			slot.Total = slot.Total.Add(entry.Nutrition)
+		}
+		sendData(w, http.StatusOK, &popEntries, &slots, &total, budget, &days, pagination)
 		return
 	})
 }
 
-func GetUsersDatesWithEntries(db *sql.DB, logger *logrus.Logger) http.Handler {
+func GetUsersDatesWithEntries(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	type Day struct {
+		Date   time.Time     `json:"date"`
+		Budget models.Budget `json:"budget"`
+	}
 	type RequestObject struct {
 	}
 	type ResponseObject struct {
 		Error string       `json:"error,omitempty"`
 		Dates *[]time.Time `json:"dates,omitempty"`
+		Days  *[]Day       `json:"days,omitempty"`
 	}
 	sendError := func(w http.ResponseWriter, status int, err error) {
 		err = errors.Wrap(err, "While creating entry")
@@ -181,11 +340,12 @@ func GetUsersDatesWithEntries(db *sql.DB, logger *logrus.Logger) http.Handler {
 		}
 		json.NewEncoder(w).Encode(out)
 	}
-	sendData := func(w http.ResponseWriter, status int, dates *[]time.Time) {
+	sendData := func(w http.ResponseWriter, status int, dates *[]time.Time, days *[]Day) {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(status)
 		out := ResponseObject{
 			Dates: dates,
+			Days:  days,
 		}
 		json.NewEncoder(w).Encode(out)
 	}
@@ -203,18 +363,247 @@ func GetUsersDatesWithEntries(db *sql.DB, logger *logrus.Logger) http.Handler {
 			sendError(w, http.StatusBadRequest, err)
 			return
 		}
-		dates, err := models.GetUsersEntryDates(db, userID)
+		dates, err := stores.Entries.GetUsersEntryDates(userID)
 		if err != nil {
 			err = errors.Wrap(err, "While fetching dates")
 			sendError(w, http.StatusBadRequest, err)
 			return
 		}
-		sendData(w, http.StatusOK, dates)
+		days := []Day{}
+		if len(*dates) > 0 {
+			from, to := (*dates)[0], (*dates)[0]
+			for _, date := range *dates {
+				if date.Before(from) {
+					from = date
+				}
+				if date.After(to) {
+					to = date
+				}
+			}
+			summaries, err := stores.Entries.GetUsersDailySummaries(userID, from, to)
+			if err != nil {
+				err = errors.Wrap(err, "While fetching daily summaries")
+				sendError(w, http.StatusBadRequest, err)
+				return
+			}
+			goals, err := stores.Goals.GetUsersGoals(userID)
+			if err != nil {
+				err = errors.Wrap(err, "While fetching goals")
+				sendError(w, http.StatusBadRequest, err)
+				return
+			}
+			for _, summary := range summaries {
+				budget := models.NewBudget(models.GoalForDate(goals, summary.Date), summary.Total.Energy)
+				days = append(days, Day{Date: summary.Date, Budget: budget})
+			}
+		}
+		sendData(w, http.StatusOK, dates, &days)
+		return
+	})
+}
+
+func GetUsersSummary(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InvalidRange = "Invalid date range"
+	const InternalError = "Internal error"
+	const maxDays = 366
+
+	type RequestObject struct {
+		From time.Time `json:"from"`
+		To   time.Time `json:"to"`
+	}
+	type ResponseObject struct {
+		Error string               `json:"error,omitempty"`
+		Total *models.Nutrition    `json:"total,omitempty"`
+		Days  *[]models.DaySummary `json:"days,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While getting summary")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, days *[]models.DaySummary, total *models.Nutrition) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Days:  days,
+			Total: total,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if in.To.Before(in.From) || in.To.Sub(in.From) > maxDays*24*time.Hour {
+			err = errors.New("Date range is reversed or too long")
+			sendError(w, http.StatusBadRequest, err, InvalidRange)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		days, err := stores.Entries.GetUsersDailySummaries(userID, in.From, in.To)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db daily summaries")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		total := models.Nutrition{}
+		for _, day := range days {
+			total = total.Add(day.Total)
+		}
+		sendData(w, http.StatusOK, &days, &total)
 		return
 	})
 }
 
-func DeleteEntry(db *sql.DB, logger *logrus.Logger) http.Handler {
+func CopyEntries(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+	const NoEntries = "There are no entries to copy"
+	const InvalidEntries = "Some of the entries are no longer valid"
+	const InvalidTargets = "Provide between 1 and 31 target dates other than source date"
+	const maxTargets = 31
+
+	type RequestObject struct {
+		From   time.Time   `json:"from"`
+		Slot   *string     `json:"slot,omitempty"`
+		To     []time.Time `json:"to"`
+		DryRun bool        `json:"dryRun"`
+	}
+	type ResponseObject struct {
+		Error    string              `json:"error,omitempty"`
+		Fields   []models.FieldError `json:"fields,omitempty"`
+		Entries  *[]models.Entry     `json:"entries,omitempty"`
+		NonEmpty *[]time.Time        `json:"nonEmpty,omitempty"`
+		DryRun   bool                `json:"dryRun,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While copying entries")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		if invalid, ok := errors.Cause(err).(*models.ValidationError); ok {
+			out.Fields = invalid.Fields
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, entries *[]models.Entry, nonEmpty *[]time.Time, dryRun bool) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Entries:  entries,
+			NonEmpty: nonEmpty,
+			DryRun:   dryRun,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		from := models.Day(in.From)
+		targets := []time.Time{}
+		seen := map[time.Time]bool{}
+		for _, date := range in.To {
+			day := models.Day(date)
+			if day.Equal(from) {
+				err = errors.New("Target date equal to source date")
+				sendError(w, http.StatusBadRequest, err, InvalidTargets)
+				return
+			}
+			if !seen[day] {
+				seen[day] = true
+				targets = append(targets, day)
+			}
+		}
+		if len(targets) == 0 || len(targets) > maxTargets {
+			err = errors.New("Invalid number of target dates")
+			sendError(w, http.StatusBadRequest, err, InvalidTargets)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		dates, err := stores.Entries.GetUsersEntryDates(userID)
+		if err != nil {
+			err = errors.Wrap(err, "While fetching dates")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		hasSource := false
+		nonEmpty := []time.Time{}
+		for _, date := range *dates {
+			day := models.Day(date)
+			if day.Equal(from) {
+				hasSource = true
+			}
+			if seen[day] {
+				nonEmpty = append(nonEmpty, day)
+			}
+		}
+		if !hasSource {
+			err = errors.New("No entries on source date")
+			sendError(w, http.StatusBadRequest, err, NoEntries)
+			return
+		}
+		entries, _, err := stores.Entries.GetUsersEntries(userID, from, from, nil)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db users entries")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		source := []models.Entry{}
+		for _, entry := range *entries {
+			if in.Slot != nil && entry.Slot != models.NormalizeSlot(*in.Slot) {
+				continue
+			}
+			source = append(source, entry)
+		}
+		if len(source) == 0 {
+			err = errors.New("No entries in source slot")
+			sendError(w, http.StatusBadRequest, err, NoEntries)
+			return
+		}
+		created, err := stores.Entries.CopyEntries(source, targets, in.DryRun)
+		if _, ok := errors.Cause(err).(*models.ValidationError); ok {
+			sendError(w, http.StatusBadRequest, err, InvalidEntries)
+			return
+		}
+		if err != nil {
+			err = errors.Wrap(err, "While copying db entries")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, &created, &nonEmpty, in.DryRun)
+		return
+	})
+}
+
+func DeleteEntry(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
 	const InternalError = "Internal error"
 	const PermissionDenied = "Permission denied"
@@ -256,7 +645,7 @@ func DeleteEntry(db *sql.DB, logger *logrus.Logger) http.Handler {
 			return
 
 		}
-		entry, err := models.GetEntry(db, in.ID)
+		entry, err := stores.Entries.GetEntry(in.ID)
 		if err != nil {
 			err = errors.Wrap(err, "While get users entry")
 			sendError(w, http.StatusBadRequest, err, InvalidData)
@@ -267,7 +656,7 @@ func DeleteEntry(db *sql.DB, logger *logrus.Logger) http.Handler {
 			sendError(w, http.StatusUnauthorized, err, PermissionDenied)
 			return
 		}
-		err = models.DeleteEntry(db, in.ID)
+		err = stores.Entries.DeleteEntry(in.ID)
 		if err != nil {
 			err = errors.Wrap(err, "While deleting db users entry")
 			sendError(w, http.StatusBadRequest, err, InternalError)
@@ -278,16 +667,20 @@ func DeleteEntry(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func UpdateEntry(db *sql.DB, logger *logrus.Logger) http.Handler {
+// UpdateEntry changes only the fields present in the request, the updated entry has to be valid as a whole
+func UpdateEntry(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
+	const InvalidEntry = "Invalid entry"
 	const InternalError = "Internal error"
 	const PermissionDenied = "Permission denied"
 	type RequestObject struct {
-		ID    int           `json:"id"`
-		Entry *models.Entry `json:"entry"`
+		ID    int                `json:"id"`
+		Entry *models.EntryPatch `json:"entry"`
 	}
 	type ResponseObject struct {
-		Error string `json:"error,omitempty"`
+		Error  string              `json:"error,omitempty"`
+		Fields []models.FieldError `json:"fields,omitempty"`
+		Entry  *models.Entry       `json:"entry,omitempty"`
 	}
 	sendError := func(w http.ResponseWriter, status int, err error, message string) {
 		err = errors.Wrap(err, "While updating entry")
@@ -297,12 +690,18 @@ func UpdateEntry(db *sql.DB, logger *logrus.Logger) http.Handler {
 		out := ResponseObject{
 			Error: err.Error(),
 		}
+		if invalid, ok := errors.Cause(err).(*models.ValidationError); ok {
+			out.Error = message
+			out.Fields = invalid.Fields
+		}
 		json.NewEncoder(w).Encode(out)
 	}
-	sendData := func(w http.ResponseWriter, status int) {
+	sendData := func(w http.ResponseWriter, status int, entry *models.Entry) {
 		w.Header().Add("Content-Type", "application/json")
 		w.WriteHeader(status)
-		out := ResponseObject{}
+		out := ResponseObject{
+			Entry: entry,
+		}
 		json.NewEncoder(w).Encode(out)
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -320,19 +719,66 @@ func UpdateEntry(db *sql.DB, logger *logrus.Logger) http.Handler {
 			return
 
 		}
-		entry, err := models.GetEntry(db, in.ID)
+		entry, err := stores.Entries.GetEntry(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While get users entry")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
 		if entry.UserID != userID {
 			err = errors.New("Permission denied, user id do not match")
 			sendError(w, http.StatusUnauthorized, err, PermissionDenied)
 			return
 		}
-		err = models.UpdateEntry(db, in.ID, in.Entry)
+		if in.Entry == nil {
+			err = errors.New("No entry provided")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		updated := in.Entry.Apply(*entry)
+		slots, err := stores.Entries.GetUsersSlots(userID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db meal slots")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		// Entry can keep slot which was removed from users slots since it was logged
+		slots = append(slots, entry.Slot)
+		// missing product or portion is reported by validation
+		product, err := stores.Products.GetProductById(updated.ProductID)
+		if err != nil && errors.Cause(err) != models.ErrNotFound {
+			err = errors.Wrap(err, "While getting db product")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		portion, err := stores.Portions.GetPortion(updated.PortionID)
+		if err != nil && errors.Cause(err) != models.ErrNotFound {
+			err = errors.Wrap(err, "While getting db portion")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		// Entry can keep product which was deleted since it was logged, only moving it to a deleted product is refused
+		if product != nil && product.Deleted && updated.ProductID == entry.ProductID {
+			kept := *product
+			kept.Deleted = false
+			product = &kept
+		}
+		err = models.ValidateEntry(updated, product, portion, slots, time.Now())
+		if err != nil {
+			if _, ok := err.(*models.ValidationError); ok {
+				sendError(w, http.StatusBadRequest, err, InvalidEntry)
+				return
+			}
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		err = stores.Entries.UpdateEntry(in.ID, &updated)
 		if err != nil {
 			err = errors.Wrap(err, "While db update entry")
 			sendError(w, http.StatusBadRequest, err, InternalError)
 			return
 		}
-		sendData(w, http.StatusOK)
+		sendData(w, http.StatusOK, &updated)
 		return
 	})
 }
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"app/service/auth"
+	"app/service/models"
+	"app/service/store"
+	"net/http"
+	"testing"
+	"time"
+)
+
+type entryResponse struct {
+	Error  string              `json:"error"`
+	Fields []models.FieldError `json:"fields"`
+	Entry  *models.Entry       `json:"entry"`
+}
+
+func TestCreateEntry(t *testing.T) {
+	stores := store.NewMemory()
+	handler := CreateEntry(stores, newTestLogger())
+	userID := createTestAccount(t, stores, "user@example.com", auth.User)
+	product, portions := createTestProduct(t, stores, userID, "Apple", "piece", "100g")
+	other, otherPortions := createTestProduct(t, stores, userID, "Pear", "piece")
+	today := time.Now()
+
+	entry := func(productID, portionID int, quantity float64, date time.Time, slot string) map[string]interface{} {
+		return map[string]interface{}{"entry": models.Entry{
+			ProductID: productID,
+			PortionID: portionID,
+			Quantity:  quantity,
+			Date:      date,
+			Slot:      slot,
+		}}
+	}
+
+	out := entryResponse{}
+	status := serve(t, handler, userID, entry(product.ID, portions[0].ID, 2, today, " Lunch "), &out)
+	if status != http.StatusOK {
+		t.Fatalf("Expected entry to be created, got %d %q %v", status, out.Error, out.Fields)
+	}
+	if out.Entry.UserID != userID || out.Entry.Slot != "lunch" || out.Entry.Quantity != 2 {
+		t.Errorf("Expected entry of the user in normalized slot, got %+v", out.Entry)
+	}
+
+	out = entryResponse{}
+	body := entry(product.ID, 0, 0, today, "dinner")
+	body["grams"] = 250
+	status = serve(t, handler, userID, body, &out)
+	if status != http.StatusOK {
+		t.Fatalf("Expected grams to be logged, got %d %q %v", status, out.Error, out.Fields)
+	}
+	if out.Entry.PortionID != portions[1].ID || out.Entry.Quantity != 2.5 {
+		t.Errorf("Expected 2.5 of the gram portion, got %+v", out.Entry)
+	}
+
+	cases := []struct {
+		name   string
+		body   map[string]interface{}
+		fields []string
+	}{
+		{"missing product", entry(-1, portions[0].ID, 1, today, "lunch"), []string{"productID"}},
+		{"portion of other product", entry(product.ID, otherPortions[0].ID, 1, today, "lunch"), []string{"portionID"}},
+		{"invalid quantity and slot", entry(product.ID, portions[0].ID, -1, today, "brunch"), []string{"quantity", "slot"}},
+		{"far future", entry(other.ID, otherPortions[0].ID, 1, today.AddDate(2, 0, 0), "lunch"), []string{"date"}},
+	}
+	for _, c := range cases {
+		out := entryResponse{}
+		status := serve(t, handler, userID, c.body, &out)
+		if status != http.StatusBadRequest || out.Error != "Invalid entry" {
+			t.Errorf("%s: expected invalid entry, got %d %q", c.name, status, out.Error)
+			continue
+		}
+		if !sameFields(out.Fields, c.fields) {
+			t.Errorf("%s: expected fields %v, got %+v", c.name, c.fields, out.Fields)
+		}
+	}
+
+	out = entryResponse{}
+	body = entry(other.ID, 0, 0, today, "lunch")
+	body["grams"] = 100
+	status = serve(t, handler, userID, body, &out)
+	if status != http.StatusBadRequest || out.Error != "Product has no portion measured in grams" {
+		t.Errorf("Expected grams of product without gram portion to be refused, got %d %q", status, out.Error)
+	}
+}
+
+func TestUpdateEntry(t *testing.T) {
+	stores := store.NewMemory()
+	handler := UpdateEntry(stores, newTestLogger())
+	userID := createTestAccount(t, stores, "user@example.com", auth.User)
+	otherID := createTestAccount(t, stores, "other@example.com", auth.User)
+	product, portions := createTestProduct(t, stores, userID, "Apple", "piece", "slice")
+	deleted, deletedPortions := createTestProduct(t, stores, userID, "Pear", "piece")
+	logged, err := stores.Entries.CreateEntry(&models.Entry{
+		UserID:    userID,
+		ProductID: deleted.ID,
+		PortionID: deletedPortions[0].ID,
+		Quantity:  1,
+		Date:      time.Now(),
+		Slot:      "lunch",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	target, err := stores.Entries.CreateEntry(&models.Entry{
+		UserID:    userID,
+		ProductID: product.ID,
+		PortionID: portions[0].ID,
+		Quantity:  1,
+		Date:      time.Now(),
+		Slot:      "lunch",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = stores.Products.DeleteProduct(deleted.ID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	update := func(userID, id int, patch models.EntryPatch) (int, entryResponse) {
+		out := entryResponse{}
+		status := serve(t, handler, userID, map[string]interface{}{"id": id, "entry": patch}, &out)
+		return status, out
+	}
+	quantity := 3.0
+	status, out := update(userID, logged.ID, models.EntryPatch{Quantity: &quantity})
+	if status != http.StatusOK {
+		t.Fatalf("Expected entry of deleted product to be editable, got %d %q %v", status, out.Error, out.Fields)
+	}
+	if out.Entry.Quantity != 3 || out.Entry.ProductID != deleted.ID {
+		t.Errorf("Expected only quantity to change, got %+v", out.Entry)
+	}
+	stored, err := stores.Entries.GetEntry(logged.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Quantity != 3 {
+		t.Errorf("Expected updated quantity to be stored, got %v", stored.Quantity)
+	}
+
+	status, out = update(userID, target.ID, models.EntryPatch{ProductID: &deleted.ID, PortionID: &deletedPortions[0].ID})
+	if status != http.StatusBadRequest || !sameFields(out.Fields, []string{"productID"}) {
+		t.Errorf("Expected move to deleted product to be refused, got %d %q %v", status, out.Error, out.Fields)
+	}
+	status, out = update(userID, target.ID, models.EntryPatch{PortionID: &deletedPortions[0].ID})
+	if status != http.StatusBadRequest || !sameFields(out.Fields, []string{"portionID"}) {
+		t.Errorf("Expected portion of other product to be refused, got %d %q %v", status, out.Error, out.Fields)
+	}
+	status, out = update(userID, target.ID, models.EntryPatch{PortionID: &portions[1].ID})
+	if status != http.StatusOK || out.Entry.PortionID != portions[1].ID {
+		t.Errorf("Expected portion to change, got %d %q %v", status, out.Error, out.Fields)
+	}
+	status, _ = update(otherID, target.ID, models.EntryPatch{Quantity: &quantity})
+	if status != http.StatusUnauthorized {
+		t.Errorf("Expected entry of other user to be refused, got %d", status)
+	}
+}
+
+func sameFields(fields []models.FieldError, names []string) bool {
+	if len(fields) != len(names) {
+		return false
+	}
+	for i, field := range fields {
+		if field.Field != names[i] {
+			return false
+		}
+	}
+	return true
+}
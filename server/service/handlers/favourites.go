@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"app/service/middleware"
+	"app/service/models"
+	"app/service/store"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func AddFavourite(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InvalidProduct = "Product does not exist"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+		ID int `json:"id"`
+	}
+	type ResponseObject struct {
+		Error string `json:"error,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While adding favourite")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		err = stores.Favourites.AddFavourite(userID, in.ID)
+		if errors.Cause(err) == models.ErrNotFound {
+			sendError(w, http.StatusBadRequest, err, InvalidProduct)
+			return
+		}
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK)
+		return
+	})
+}
+
+func RemoveFavourite(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+		ID int `json:"id"`
+	}
+	type ResponseObject struct {
+		Error string `json:"error,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While removing favourite")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		err = stores.Favourites.RemoveFavourite(userID, in.ID)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK)
+		return
+	})
+}
+
+func GetFavourites(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+
+	type Product struct {
+		models.Product
+		Portions  []models.Portion `json:"portions"`
+		Favourite bool             `json:"favourite"`
+	}
+	type RequestObject struct {
+		Pagination models.Pagination `json:"pagination"`
+	}
+	type ResponseObject struct {
+		Error      string            `json:"error,omitempty"`
+		Products   []Product         `json:"products,omitempty"`
+		Pagination models.Pagination `json:"pagination"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While getting favourites")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, products []Product, pagination models.Pagination) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Products:   products,
+			Pagination: pagination,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		products, pagination, err := stores.Favourites.GetUsersFavourites(userID, in.Pagination)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db favourites")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		bundledProducts := []Product{}
+		for _, product := range *products {
+			portions, err := stores.Portions.GetProductsPortions(product.ID)
+			if err != nil {
+				err = errors.Wrap(err, "While getting db product portions")
+				sendError(w, http.StatusBadRequest, err, InternalError)
+				return
+			}
+			bundledProducts = append(bundledProducts, Product{Product: product, Portions: portions, Favourite: true})
+		}
+		sendData(w, http.StatusOK, bundledProducts, *pagination)
+		return
+	})
+}
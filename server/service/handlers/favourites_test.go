@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"app/service/auth"
+	"app/service/models"
+	"app/service/store"
+	"net/http"
+	"testing"
+)
+
+func TestFavourites(t *testing.T) {
+	stores := store.NewMemory()
+	logger := newTestLogger()
+	userID := createTestAccount(t, stores, "user@example.com", auth.User)
+	otherID := createTestAccount(t, stores, "other@example.com", auth.User)
+	apple, _ := createTestProduct(t, stores, userID, "Apple", "piece")
+	createTestProduct(t, stores, userID, "Apple pie", "slice")
+	deleted, _ := createTestProduct(t, stores, userID, "Apple juice", "glass")
+
+	type response struct {
+		Error string `json:"error"`
+	}
+	out := response{}
+	status := serve(t, AddFavourite(stores, logger), userID, map[string]interface{}{"id": apple.ID}, &out)
+	if status != http.StatusOK {
+		t.Fatalf("Expected favourite to be added, got %d %q", status, out.Error)
+	}
+	err := stores.Products.DeleteProduct(deleted.ID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out = response{}
+	status = serve(t, AddFavourite(stores, logger), userID, map[string]interface{}{"id": deleted.ID}, &out)
+	if status == http.StatusOK {
+		t.Errorf("Expected deleted product not to become favourite")
+	}
+
+	search := func(userID int, favouritesOnly bool) []models.ScoredProduct {
+		products, _, err := stores.Products.GetProductsByName("apple", userID, favouritesOnly, models.Pagination{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return *products
+	}
+	if found := search(userID, true); len(found) != 1 || found[0].ID != apple.ID || !found[0].Favourite {
+		t.Errorf("Expected only the favourite apple, got %+v", found)
+	}
+	if found := search(userID, false); len(found) != 2 {
+		t.Errorf("Expected both products which are not deleted, got %+v", found)
+	}
+	if found := search(otherID, true); len(found) != 0 {
+		t.Errorf("Expected favourites of other user to be empty, got %+v", found)
+	}
+	favourite, err := stores.Favourites.IsFavourite(userID, apple.ID)
+	if err != nil || !favourite {
+		t.Errorf("Expected apple to be favourite, got %v %v", favourite, err)
+	}
+
+	out = response{}
+	status = serve(t, RemoveFavourite(stores, logger), userID, map[string]interface{}{"id": apple.ID}, &out)
+	if status != http.StatusOK {
+		t.Fatalf("Expected favourite to be removed, got %d %q", status, out.Error)
+	}
+	if found := search(userID, true); len(found) != 0 {
+		t.Errorf("Expected no favourites after removing, got %+v", found)
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"app/service/middleware"
+	"app/service/models"
+	"app/service/store"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func GetFrequentProducts(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InvalidLimit = "Limit has to be between 1 and 50"
+	const InternalError = "Internal error"
+	const DefaultLimit = 10
+	const MaxLimit = 50
+
+	type Product struct {
+		models.Product
+		Portions []models.Portion `json:"portions"`
+	}
+	type LoggedProduct struct {
+		models.LoggedProduct
+		Product Product `json:"product"`
+	}
+	type RequestObject struct {
+		Date  time.Time `json:"date,omitempty"`
+		Limit int       `json:"limit,omitempty"`
+	}
+	type ResponseObject struct {
+		Error    string          `json:"error,omitempty"`
+		Recent   []LoggedProduct `json:"recent,omitempty"`
+		Frequent []LoggedProduct `json:"frequent,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While getting frequent products")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, recent, frequent []LoggedProduct) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Recent:   recent,
+			Frequent: frequent,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		limit := in.Limit
+		if limit == 0 {
+			limit = DefaultLimit
+		}
+		if limit < 0 || limit > MaxLimit {
+			err = errors.New("Limit out of range")
+			sendError(w, http.StatusBadRequest, err, InvalidLimit)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		date := in.Date
+		if date.IsZero() {
+			date = time.Now()
+		}
+		recent, err := stores.Entries.GetUsersRecentProducts(userID, models.Day(date), limit)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db recent products")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		frequent, err := stores.Entries.GetUsersFrequentProducts(userID, models.Day(date), limit)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db frequent products")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		products := map[int]Product{}
+		populate := func(logged []models.LoggedProduct) ([]LoggedProduct, error) {
+			populated := []LoggedProduct{}
+			for _, item := range logged {
+				product, ok := products[item.ProductID]
+				if !ok {
+					dbProduct, err := stores.Products.GetProductById(item.ProductID)
+					if err != nil {
+						return nil, errors.Wrap(err, "While getting db product")
+					}
+					portions, err := stores.Portions.GetProductsPortions(item.ProductID)
+					if err != nil {
+						return nil, errors.Wrap(err, "While getting db product portions")
+					}
+					product = Product{Product: *dbProduct, Portions: portions}
+					products[item.ProductID] = product
+				}
+				populated = append(populated, LoggedProduct{LoggedProduct: item, Product: product})
+			}
+			return populated, nil
+		}
+		populatedRecent, err := populate(recent)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		populatedFrequent, err := populate(frequent)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, populatedRecent, populatedFrequent)
+		return
+	})
+}
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"app/service/middleware"
+	"app/service/models"
+	"app/service/store"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func SetGoal(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InvalidEnergy = "Goal energy has to be positive"
+	const InvalidWeekday = "Weekday has to be between 0 (Sunday) and 6 (Saturday)"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+		Energy        float64   `json:"energy"`
+		Weekday       *int      `json:"weekday,omitempty"`
+		EffectiveFrom time.Time `json:"effectiveFrom,omitempty"`
+	}
+	type ResponseObject struct {
+		Error string       `json:"error,omitempty"`
+		Goal  *models.Goal `json:"goal,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While setting goal")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, goal *models.Goal) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Goal: goal,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if in.Energy <= 0 {
+			err = errors.New("Non positive goal energy")
+			sendError(w, http.StatusBadRequest, err, InvalidEnergy)
+			return
+		}
+		if in.Weekday != nil && (*in.Weekday < 0 || *in.Weekday > 6) {
+			err = errors.New("Weekday out of range")
+			sendError(w, http.StatusBadRequest, err, InvalidWeekday)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		effectiveFrom := in.EffectiveFrom
+		if effectiveFrom.IsZero() {
+			effectiveFrom = time.Now()
+		}
+		goal := models.Goal{
+			UserID:        userID,
+			Energy:        in.Energy,
+			Weekday:       in.Weekday,
+			EffectiveFrom: models.Day(effectiveFrom),
+		}
+		dbGoal, err := stores.Goals.SetGoal(goal)
+		if err != nil {
+			err = errors.Wrap(err, "While setting db goal")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, dbGoal)
+		return
+	})
+}
+
+func GetGoals(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+		Date time.Time `json:"date,omitempty"`
+	}
+	type ResponseObject struct {
+		Error   string         `json:"error,omitempty"`
+		Goals   *[]models.Goal `json:"goals,omitempty"`
+		Current *models.Goal   `json:"current,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While getting goals")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, goals *[]models.Goal, current *models.Goal) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Goals:   goals,
+			Current: current,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		goals, err := stores.Goals.GetUsersGoals(userID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db goals")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		date := in.Date
+		if date.IsZero() {
+			date = time.Now()
+		}
+		sendData(w, http.StatusOK, &goals, models.GoalForDate(goals, date))
+		return
+	})
+}
+
+func DeleteGoal(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+	const PermissionDenied = "Permission denied"
+
+	type RequestObject struct {
+		ID int `json:"id"`
+	}
+	type ResponseObject struct {
+		Error string `json:"error,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While deleting goal")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		goal, err := stores.Goals.GetGoal(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db goal")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if goal.UserID != userID {
+			err = errors.New("Permission denied, user id do not match")
+			sendError(w, http.StatusUnauthorized, err, PermissionDenied)
+			return
+		}
+		err = stores.Goals.DeleteGoal(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While deleting db goal")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK)
+		return
+	})
+}
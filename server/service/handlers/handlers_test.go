@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"app/service/auth"
+	"app/service/middleware"
+	"app/service/models"
+	"app/service/store"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+)
+
+func newTestLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetOutput(ioutil.Discard)
+	return logger
+}
+
+// serve sends body as json to the handler on behalf of the user and decodes the response into out
+func serve(t *testing.T, handler http.Handler, userID int, body interface{}, out interface{}) int {
+	t.Helper()
+	in, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/", bytes.NewReader(in))
+	r = r.WithContext(context.WithValue(r.Context(), middleware.UserID, userID))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+	err = json.NewDecoder(w.Body).Decode(out)
+	if err != nil {
+		t.Fatalf("While decoding response: %v", err)
+	}
+	return w.Code
+}
+
+func createTestAccount(t *testing.T, stores *store.Stores, email string, accessLevel auth.AccessLevel) int {
+	t.Helper()
+	err := stores.Accounts.CreateAccount(&models.Account{Email: email, Password: "password", AccessLevel: accessLevel})
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc, err := stores.Accounts.GetAccountByEmail(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return acc.ID
+}
+
+func createTestProduct(t *testing.T, stores *store.Stores, creator int, name string, units ...string) (*models.Product, []models.Portion) {
+	t.Helper()
+	portions := []models.Portion{}
+	for _, unit := range units {
+		portions = append(portions, models.Portion{Unit: unit, Nutrition: models.Nutrition{Energy: 100}})
+	}
+	product, portions, err := stores.Products.CreateProductWithPortions(models.Product{Name: name, Creator: creator}, portions, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return product, portions
+}
@@ -0,0 +1,384 @@
+package handlers
+
+import (
+	"app/service/auth"
+	"app/service/middleware"
+	"app/service/models"
+	"app/service/store"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// canEditProduct checks if user is the creator of the product or a moderator
+func canEditProduct(accounts store.AccountStore, userID int, product *models.Product) (bool, error) {
+	if product.Creator == userID {
+		return true, nil
+	}
+	acc, err := accounts.GetAccountById(userID)
+	if err != nil {
+		return false, errors.Wrap(err, "While getting account by id")
+	}
+	return acc.AccessLevel >= auth.Moderator, nil
+}
+
+func AddPortion(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InvalidPortion = "Invalid portion"
+	const NotFound = "No product with that id"
+	const AccessDenied = "Only creator of the product or a moderator can change its portions"
+	const RecipePortions = "Portions of a recipe are computed from its ingredients"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+		Portion *models.Portion `json:"portion"`
+	}
+	type ResponseObject struct {
+		Error   string          `json:"error,omitempty"`
+		Portion *models.Portion `json:"portion,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While adding portion")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, portion *models.Portion) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Portion: portion,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if in.Portion == nil {
+			err = errors.New("No portion provided")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		err = in.Portion.Validate()
+		if err != nil {
+			err = errors.Wrap(err, "While validating portion")
+			sendError(w, http.StatusBadRequest, err, InvalidPortion)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		product, err := stores.Products.GetProductById(in.Portion.ProductID)
+		if err != nil || product.Deleted {
+			err = errors.Wrap(err, "While getting db product")
+			sendError(w, http.StatusBadRequest, err, NotFound)
+			return
+		}
+		if product.Recipe {
+			err = errors.New(RecipePortions)
+			sendError(w, http.StatusBadRequest, err, RecipePortions)
+			return
+		}
+		allowed, err := canEditProduct(stores.Accounts, userID, product)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		if !allowed {
+			err = errors.New(AccessDenied)
+			sendError(w, http.StatusForbidden, err, AccessDenied)
+			return
+		}
+		portion, err := stores.Portions.AddProductPortion(*in.Portion, userID)
+		if err != nil {
+			err = errors.Wrap(err, "While adding db portion")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, portion)
+		return
+	})
+}
+
+func UpdatePortion(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InvalidPortion = "Invalid portion"
+	const NotFound = "No portion with that id"
+	const AccessDenied = "Only creator of the product or a moderator can change its portions"
+	const RecipePortions = "Portions of a recipe are computed from its ingredients"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+		Portion *models.Portion `json:"portion"`
+	}
+	type ResponseObject struct {
+		Error   string          `json:"error,omitempty"`
+		Portion *models.Portion `json:"portion,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While updating portion")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, portion *models.Portion) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Portion: portion,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if in.Portion == nil {
+			err = errors.New("No portion provided")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		err = in.Portion.Validate()
+		if err != nil {
+			err = errors.Wrap(err, "While validating portion")
+			sendError(w, http.StatusBadRequest, err, InvalidPortion)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		current, err := stores.Portions.GetPortion(in.Portion.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db portion")
+			sendError(w, http.StatusBadRequest, err, NotFound)
+			return
+		}
+		product, err := stores.Products.GetProductById(current.ProductID)
+		if err != nil || product.Deleted {
+			err = errors.Wrap(err, "While getting db product")
+			sendError(w, http.StatusBadRequest, err, NotFound)
+			return
+		}
+		if product.Recipe {
+			err = errors.New(RecipePortions)
+			sendError(w, http.StatusBadRequest, err, RecipePortions)
+			return
+		}
+		allowed, err := canEditProduct(stores.Accounts, userID, product)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		if !allowed {
+			err = errors.New(AccessDenied)
+			sendError(w, http.StatusForbidden, err, AccessDenied)
+			return
+		}
+		in.Portion.ProductID = current.ProductID
+		portion, err := stores.Portions.EditProductPortion(*in.Portion, userID)
+		if err != nil {
+			err = errors.Wrap(err, "While updating db portion")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, portion)
+		return
+	})
+}
+
+func RemovePortion(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const NotFound = "No portion with that id"
+	const AccessDenied = "Only creator of the product or a moderator can change its portions"
+	const RecipePortions = "Portions of a recipe are computed from its ingredients"
+	const CannotRemove = "Portion is in use or is the last portion of the product"
+
+	type RequestObject struct {
+		ID int `json:"id"`
+	}
+	type ResponseObject struct {
+		Error string `json:"error,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While removing portion")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		portion, err := stores.Portions.GetPortion(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db portion")
+			sendError(w, http.StatusBadRequest, err, NotFound)
+			return
+		}
+		product, err := stores.Products.GetProductById(portion.ProductID)
+		if err != nil || product.Deleted {
+			err = errors.Wrap(err, "While getting db product")
+			sendError(w, http.StatusBadRequest, err, NotFound)
+			return
+		}
+		if product.Recipe {
+			err = errors.New(RecipePortions)
+			sendError(w, http.StatusBadRequest, err, RecipePortions)
+			return
+		}
+		allowed, err := canEditProduct(stores.Accounts, userID, product)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if !allowed {
+			err = errors.New(AccessDenied)
+			sendError(w, http.StatusForbidden, err, AccessDenied)
+			return
+		}
+		err = stores.Portions.RemoveProductPortion(portion.ProductID, portion.ID, userID)
+		if err != nil {
+			err = errors.Wrap(err, "While removing db portion")
+			sendError(w, http.StatusBadRequest, err, CannotRemove)
+			return
+		}
+		sendData(w, http.StatusOK)
+		return
+	})
+}
+
+func ConvertPortion(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const NotFound = "No portion with that id"
+	const CannotConvert = "Portions cannot be converted"
+
+	// Either FromPortionID with Quantity or Amount with BaseUnit ("g" or "ml") describe converted amount
+	type RequestObject struct {
+		FromPortionID int     `json:"fromPortionID,omitempty"`
+		Quantity      float64 `json:"quantity,omitempty"`
+		Amount        float64 `json:"amount,omitempty"`
+		BaseUnit      string  `json:"baseUnit,omitempty"`
+		ToPortionID   int     `json:"toPortionID"`
+	}
+	type ResponseObject struct {
+		Error     string            `json:"error,omitempty"`
+		Quantity  float64           `json:"quantity"`
+		Nutrition *models.Nutrition `json:"nutrition,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While converting portion")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, quantity float64, nutrition models.Nutrition) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Quantity:  quantity,
+			Nutrition: &nutrition,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		to, err := stores.Portions.GetPortion(in.ToPortionID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting target portion")
+			sendError(w, http.StatusBadRequest, err, NotFound)
+			return
+		}
+		var quantity float64
+		if in.FromPortionID != 0 {
+			if in.Quantity <= 0 {
+				err = errors.New("Quantity has to be positive")
+				sendError(w, http.StatusBadRequest, err, InvalidData)
+				return
+			}
+			from, err := stores.Portions.GetPortion(in.FromPortionID)
+			if err != nil {
+				err = errors.Wrap(err, "While getting source portion")
+				sendError(w, http.StatusBadRequest, err, NotFound)
+				return
+			}
+			if from.ProductID != to.ProductID {
+				err = errors.New("Portions belong to different products")
+				sendError(w, http.StatusBadRequest, err, CannotConvert)
+				return
+			}
+			quantity, err = models.ConvertQuantity(*from, in.Quantity, *to)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, err, CannotConvert)
+				return
+			}
+		} else {
+			if in.Amount <= 0 {
+				err = errors.New("Amount has to be positive")
+				sendError(w, http.StatusBadRequest, err, InvalidData)
+				return
+			}
+			quantity, err = models.QuantityOfAmount(*to, in.Amount, in.BaseUnit)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, err, CannotConvert)
+				return
+			}
+		}
+		sendData(w, http.StatusOK, quantity, to.Nutrition.Scale(quantity))
+		return
+	})
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"app/service/auth"
+	"app/service/models"
+	"app/service/store"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAddPortion(t *testing.T) {
+	stores := store.NewMemory()
+	handler := AddPortion(stores, newTestLogger())
+	creatorID := createTestAccount(t, stores, "creator@example.com", auth.User)
+	otherID := createTestAccount(t, stores, "other@example.com", auth.User)
+	moderatorID := createTestAccount(t, stores, "moderator@example.com", auth.Moderator)
+	product, _ := createTestProduct(t, stores, creatorID, "Apple", "piece")
+	deleted, _ := createTestProduct(t, stores, creatorID, "Pear", "piece")
+	err := stores.Products.DeleteProduct(deleted.ID, creatorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type response struct {
+		Error   string          `json:"error"`
+		Portion *models.Portion `json:"portion"`
+	}
+	portion := func(productID int, unit string) map[string]interface{} {
+		return map[string]interface{}{
+			"portion": models.Portion{ProductID: productID, Unit: unit, Nutrition: models.Nutrition{Energy: 50}},
+		}
+	}
+
+	cases := []struct {
+		name    string
+		userID  int
+		body    map[string]interface{}
+		status  int
+		message string
+	}{
+		{"creator", creatorID, portion(product.ID, "100g"), http.StatusOK, ""},
+		{"moderator", moderatorID, portion(product.ID, "slice"), http.StatusOK, ""},
+		{"other user", otherID, portion(product.ID, "cup"), http.StatusForbidden, "Only creator of the product or a moderator can change its portions"},
+		{"deleted product", creatorID, portion(deleted.ID, "cup"), http.StatusBadRequest, "No product with that id"},
+		{"missing product", creatorID, portion(-1, "cup"), http.StatusBadRequest, "No product with that id"},
+		{"invalid portion", creatorID, portion(product.ID, ""), http.StatusBadRequest, "Invalid portion"},
+	}
+	for _, c := range cases {
+		out := response{}
+		status := serve(t, handler, c.userID, c.body, &out)
+		if status != c.status || out.Error != c.message {
+			t.Errorf("%s: expected %d %q, got %d %q", c.name, c.status, c.message, status, out.Error)
+		}
+	}
+	portions, err := stores.Portions.GetProductsPortions(product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(portions) != 3 {
+		t.Errorf("Expected product to have 3 portions, got %d", len(portions))
+	}
+}
+
+func TestRemovePortion(t *testing.T) {
+	stores := store.NewMemory()
+	handler := RemovePortion(stores, newTestLogger())
+	creatorID := createTestAccount(t, stores, "creator@example.com", auth.User)
+	otherID := createTestAccount(t, stores, "other@example.com", auth.User)
+	_, portions := createTestProduct(t, stores, creatorID, "Apple", "piece", "slice", "cup")
+	_, single := createTestProduct(t, stores, creatorID, "Pear", "piece")
+	_, err := stores.Entries.CreateEntry(&models.Entry{
+		UserID:    creatorID,
+		ProductID: portions[1].ProductID,
+		PortionID: portions[1].ID,
+		Quantity:  1,
+		Date:      time.Now(),
+		Slot:      "lunch",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type response struct {
+		Error string `json:"error"`
+	}
+	const CannotRemove = "Portion is in use or is the last portion of the product"
+	cases := []struct {
+		name      string
+		userID    int
+		portionID int
+		status    int
+		message   string
+	}{
+		{"other user", otherID, portions[0].ID, http.StatusForbidden, "Only creator of the product or a moderator can change its portions"},
+		{"unused portion", creatorID, portions[0].ID, http.StatusOK, ""},
+		{"removed portion", creatorID, portions[0].ID, http.StatusBadRequest, "No portion with that id"},
+		{"portion in use", creatorID, portions[1].ID, http.StatusBadRequest, CannotRemove},
+		{"last portion", creatorID, single[0].ID, http.StatusBadRequest, CannotRemove},
+	}
+	for _, c := range cases {
+		out := response{}
+		status := serve(t, handler, c.userID, map[string]interface{}{"id": c.portionID}, &out)
+		if status != c.status || out.Error != c.message {
+			t.Errorf("%s: expected %d %q, got %d %q", c.name, c.status, c.message, status, out.Error)
+		}
+	}
+}
+
+func TestUpdatePortion(t *testing.T) {
+	stores := store.NewMemory()
+	handler := UpdatePortion(stores, newTestLogger())
+	creatorID := createTestAccount(t, stores, "creator@example.com", auth.User)
+	_, portions := createTestProduct(t, stores, creatorID, "Apple", "piece")
+	deleted, deletedPortions := createTestProduct(t, stores, creatorID, "Pear", "piece")
+	err := stores.Products.DeleteProduct(deleted.ID, creatorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type response struct {
+		Error   string          `json:"error"`
+		Portion *models.Portion `json:"portion"`
+	}
+	portion := func(id int, energy float64) map[string]interface{} {
+		return map[string]interface{}{
+			"portion": models.Portion{ID: id, Unit: "piece", Nutrition: models.Nutrition{Energy: energy}},
+		}
+	}
+
+	out := response{}
+	status := serve(t, handler, creatorID, portion(portions[0].ID, 80), &out)
+	if status != http.StatusOK || out.Portion.Energy != 80 || out.Portion.ProductID != portions[0].ProductID {
+		t.Errorf("Expected portion to be updated, got %d %q %+v", status, out.Error, out.Portion)
+	}
+	out = response{}
+	status = serve(t, handler, creatorID, portion(deletedPortions[0].ID, 80), &out)
+	if status != http.StatusBadRequest || out.Error != "No portion with that id" {
+		t.Errorf("Expected portion of deleted product to be refused, got %d %q", status, out.Error)
+	}
+}
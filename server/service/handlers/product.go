@@ -4,7 +4,7 @@ import (
 	"app/service/auth"
 	"app/service/middleware"
 	"app/service/models"
-	"database/sql"
+	"app/service/store"
 	"encoding/json"
 	"log"
 	"net/http"
@@ -14,16 +14,20 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-func CreateProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
+func CreateProduct(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
 	const InternalError = "Internal error"
 	const AlreadyExists = "Product with same name already exists"
-	const TooManyPortions = "Entered too many portions"
+	const EmptyName = "Product name cannot be empty"
 	const TooFewPortions = "Entered too few portions"
+	const InvalidPortion = "Invalid portion"
+	const InvalidBarcode = "Invalid barcode"
+	const BarcodeExists = "Barcode is already assigned to another product"
 
 	type Product struct {
 		*models.Product
 		Portions *[]models.Portion `json:"portions"`
+		Barcodes []string          `json:"barcodes,omitempty"`
 	}
 	type RequestObject struct {
 		Product *Product `json:"product"`
@@ -69,16 +73,44 @@ func CreateProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
 			return
 		}
 		portions := *in.Product.Portions
-		if len(portions) > 5 {
-			err = errors.New(TooManyPortions)
-			sendError(w, http.StatusBadRequest, err, TooManyPortions)
-			return
-		}
 		if len(portions) == 0 {
 			err = errors.New(TooFewPortions)
 			sendError(w, http.StatusBadRequest, err, TooFewPortions)
 			return
 		}
+		for _, portion := range portions {
+			err = portion.Validate()
+			if err != nil {
+				err = errors.Wrap(err, "While validating portion")
+				sendError(w, http.StatusBadRequest, err, InvalidPortion)
+				return
+			}
+		}
+		barcodes := []string{}
+		seenBarcodes := map[string]bool{}
+		for _, code := range in.Product.Barcodes {
+			barcode, err := models.NormalizeBarcode(code)
+			if err != nil {
+				err = errors.Wrap(err, "While validating barcode")
+				sendError(w, http.StatusBadRequest, err, InvalidBarcode)
+				return
+			}
+			if seenBarcodes[barcode] {
+				continue
+			}
+			seenBarcodes[barcode] = true
+			exists, err := stores.Products.BarcodeExists(barcode)
+			if err != nil {
+				sendError(w, http.StatusBadRequest, err, InternalError)
+				return
+			}
+			if exists {
+				err = errors.New(BarcodeExists)
+				sendError(w, http.StatusBadRequest, err, BarcodeExists)
+				return
+			}
+			barcodes = append(barcodes, barcode)
+		}
 		userID, ok := r.Context().Value(middleware.UserID).(int)
 		if !ok {
 			err = errors.Wrap(err, "While getting UserID from request context")
@@ -92,10 +124,22 @@ func CreateProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
 			Description: in.Product.Description,
 		}
 		log.Println(newProduct)
-		dbProduct, err := models.CreateProduct(db, newProduct)
+		dbProduct, dbPortions, err := stores.Products.CreateProductWithPortions(newProduct, portions, barcodes)
 		if err != nil {
-			if pgerr, ok := err.(*pq.Error); ok {
+			if errors.Cause(err) == models.ErrProductExists {
+				sendError(w, http.StatusBadRequest, err, AlreadyExists)
+				return
+			}
+			if errors.Cause(err) == models.ErrEmptyProductName {
+				sendError(w, http.StatusBadRequest, err, EmptyName)
+				return
+			}
+			if pgerr, ok := errors.Cause(err).(*pq.Error); ok {
 				if pgerr.Code == "23505" {
+					if pgerr.Constraint == "barcodes_pkey" {
+						sendError(w, http.StatusBadRequest, err, BarcodeExists)
+						return
+					}
 					sendError(w, http.StatusBadRequest, err, AlreadyExists)
 					return
 				}
@@ -104,32 +148,24 @@ func CreateProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
 			sendError(w, http.StatusBadRequest, err, InternalError)
 			return
 		}
-		dbPortions := []models.Portion{}
-		for _, portion := range *in.Product.Portions {
-			portion.ProductID = dbProduct.ID
-			dbPortion, err := models.CreatePortion(db, portion)
-			if err != nil {
-				err = errors.Wrap(err, "While creating portion for product")
-				sendError(w, http.StatusBadRequest, err, InternalError)
-				return
-			}
-			dbPortions = append(dbPortions, *dbPortion)
-		}
 		createdProduct := Product{
 			Product:  dbProduct,
 			Portions: &dbPortions,
+			Barcodes: barcodes,
 		}
 		sendData(w, http.StatusOK, createdProduct)
 		return
 	})
 }
 
-func GetProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
+func GetProduct(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
 	const InternalError = "Internal error"
 	type Product struct {
 		*models.Product
-		Portions []models.Portion `json:"portions,omitempty"`
+		Portions  []models.Portion `json:"portions,omitempty"`
+		Barcodes  []string         `json:"barcodes,omitempty"`
+		Favourite bool             `json:"favourite"`
 	}
 	type RequestObject struct {
 		ID int `json:"id"`
@@ -164,26 +200,119 @@ func GetProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
 			sendError(w, http.StatusBadRequest, err, InvalidData)
 			return
 		}
-		product, err := models.GetProductById(db, in.ID)
+		product, err := stores.Products.GetProductById(in.ID)
 		if err != nil {
 			err = errors.Wrap(err, "While fetching products")
 			sendError(w, http.StatusBadRequest, err, InternalError)
 			return
 		}
-		portions, err := models.GetProductsPortions(db, in.ID)
+		portions, err := stores.Portions.GetProductsPortions(in.ID)
 		if err != nil {
 			err = errors.Wrap(err, "While fetching products portions")
 			sendError(w, http.StatusBadRequest, err, InternalError)
 			return
 		}
-		sendData(w, http.StatusOK, Product{Product: product, Portions: portions})
+		barcodes, err := stores.Products.GetProductsBarcodes(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While fetching products barcodes")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		favourite, err := stores.Favourites.IsFavourite(userID, in.ID)
+		if err != nil {
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, Product{Product: product, Portions: portions, Barcodes: barcodes, Favourite: favourite})
 		return
 	})
 }
 
-func UpdateProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
+func GetProductByBarcode(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
+	const InvalidBarcode = "Invalid barcode"
+	const NotFound = "No product with that barcode"
 	const InternalError = "Internal error"
+	type Product struct {
+		*models.Product
+		Portions []models.Portion `json:"portions,omitempty"`
+		Barcodes []string         `json:"barcodes,omitempty"`
+	}
+	type RequestObject struct {
+		Code string `json:"code"`
+	}
+	type ResponseObject struct {
+		Error   string   `json:"error,omitempty"`
+		Product *Product `json:"product,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While getting product by barcode")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, product *Product) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Product: product,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		code, err := models.NormalizeBarcode(in.Code)
+		if err != nil {
+			err = errors.Wrap(err, "While validating barcode")
+			sendError(w, http.StatusBadRequest, err, InvalidBarcode)
+			return
+		}
+		product, err := stores.Products.GetProductByBarcode(code)
+		if err != nil {
+			err = errors.Wrap(err, "While fetching product")
+			sendError(w, http.StatusNotFound, err, NotFound)
+			return
+		}
+		portions, err := stores.Portions.GetProductsPortions(product.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While fetching products portions")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		barcodes, err := stores.Products.GetProductsBarcodes(product.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While fetching products barcodes")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, &Product{Product: product, Portions: portions, Barcodes: barcodes})
+		return
+	})
+}
+
+func UpdateProduct(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+	const NotFound = "No product with that id"
+	const AlreadyExists = "Product with same name already exists"
+	const EmptyName = "Product name cannot be empty"
+	const Deleted = "Deleted product has to be restored before it is changed"
 	type RequestObject struct {
 		ID         int            `json:"id"`
 		NewProduct models.Product `json:"product"`
@@ -215,9 +344,32 @@ func UpdateProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
 			sendError(w, http.StatusBadRequest, err, InvalidData)
 			return
 		}
-		_, err = models.UpdateProduct(db, in.ID, in.NewProduct)
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		_, err = stores.Products.UpdateProduct(in.ID, in.NewProduct, userID)
 		if err != nil {
 			err = errors.Wrap(err, "While updating products")
+			cause := errors.Cause(err)
+			if cause == models.ErrNotFound {
+				sendError(w, http.StatusBadRequest, err, NotFound)
+				return
+			}
+			if cause == models.ErrProductExists {
+				sendError(w, http.StatusBadRequest, err, AlreadyExists)
+				return
+			}
+			if cause == models.ErrEmptyProductName {
+				sendError(w, http.StatusBadRequest, err, EmptyName)
+				return
+			}
+			if cause == models.ErrProductDeleted {
+				sendError(w, http.StatusBadRequest, err, Deleted)
+				return
+			}
 			sendError(w, http.StatusBadRequest, err, InternalError)
 			return
 		}
@@ -226,8 +378,9 @@ func UpdateProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func DeleteProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
+func DeleteProduct(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
+	const NotFound = "No product with that id"
 	const InternalError = "Internal error"
 
 	type RequestObject struct {
@@ -260,28 +413,81 @@ func DeleteProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
 			sendError(w, http.StatusBadRequest, err, InvalidData)
 			return
 		}
-		err = models.DeleteProduct(db, in.ID)
-		if err != nil {
-			err = errors.Wrap(err, "While updating products")
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
 			sendError(w, http.StatusBadRequest, err, InternalError)
 			return
 		}
+		err = stores.Products.DeleteProduct(in.ID, userID)
+		if err != nil {
+			err = errors.Wrap(err, "While deleting db product")
+			sendError(w, http.StatusBadRequest, err, NotFound)
+			return
+		}
 		sendData(w, http.StatusOK)
 		return
 	})
 }
-func SearchProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
+
+func RestoreProduct(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const NotFound = "No deleted product with that id"
+
+	type RequestObject struct {
+		ID int `json:"id"`
+	}
+	type ResponseObject struct {
+		Error string `json:"error,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While restoring product")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		err = stores.Products.RestoreProduct(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While restoring db product")
+			sendError(w, http.StatusBadRequest, err, NotFound)
+			return
+		}
+		sendData(w, http.StatusOK)
+		return
+	})
+}
+
+func SearchProduct(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
 	const InternalError = "Internal error"
 
 	type Product struct {
-		models.Product
-		Portions []models.Portion `json:"portions"`
-		Ratings  []models.Rating  `json:"ratings"`
+		models.ScoredProduct
+		Portions []models.Portion    `json:"portions"`
+		Votes    *models.VoteSummary `json:"votes"`
 	}
 	type RequestObject struct {
-		Name       string            `json:"name"`
-		Pagination models.Pagination `json:"pagination"`
+		Name           string            `json:"name"`
+		FavouritesOnly bool              `json:"favouritesOnly,omitempty"`
+		Pagination     models.Pagination `json:"pagination"`
 	}
 	type ResponseObject struct {
 		Error      string            `json:"error,omitempty"`
@@ -323,7 +529,7 @@ func SearchProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
 			sendError(w, http.StatusBadRequest, err, InternalError)
 			return
 		}
-		products, pagination, err := models.GetProductsByName(db, in.Name, in.Pagination)
+		products, pagination, err := stores.Products.GetProductsByName(in.Name, userID, in.FavouritesOnly, in.Pagination)
 		if err != nil {
 			err = errors.Wrap(err, "While fetching products")
 			sendError(w, http.StatusBadRequest, err, InternalError)
@@ -331,22 +537,22 @@ func SearchProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
 		}
 		bundledProducts := []Product{}
 		for _, product := range *products {
-			portions, err := models.GetProductsPortions(db, product.ID)
+			portions, err := stores.Portions.GetProductsPortions(product.ID)
 			if err != nil {
 				err = errors.Wrap(err, "While products portions")
 				sendError(w, http.StatusBadRequest, err, InternalError)
 				return
 			}
-			ratings, err := models.GetProductVotes(db, product.ID)
+			votes, err := stores.Votes.GetProductVoteSummary(product.ID, userID)
 			if err != nil {
-				err = errors.Wrap(err, "While fetching ratings for product")
+				err = errors.Wrap(err, "While fetching votes for product")
 				sendError(w, http.StatusBadRequest, err, InternalError)
 				return
 			}
 			bundledProduct := Product{
-				Product:  product,
-				Portions: portions,
-				Ratings:  ratings,
+				ScoredProduct: product,
+				Portions:      portions,
+				Votes:         votes,
 			}
 			bundledProducts = append(bundledProducts, bundledProduct)
 		}
@@ -355,14 +561,14 @@ func SearchProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func GetUsersAddedProducts(db *sql.DB, logger *logrus.Logger) http.Handler {
+func GetUsersAddedProducts(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
 	const InternalError = "Internal error"
 
 	type Product struct {
 		models.Product
-		Portions []models.Portion `json:"portions"`
-		Ratings  []models.Rating  `json:"ratings"`
+		Portions []models.Portion    `json:"portions"`
+		Votes    *models.VoteSummary `json:"votes"`
 	}
 	type User struct {
 		Email       string           `json:"email"`
@@ -407,7 +613,13 @@ func GetUsersAddedProducts(db *sql.DB, logger *logrus.Logger) http.Handler {
 			sendError(w, http.StatusBadRequest, err, InvalidData)
 			return
 		}
-		products, pagination, err := models.GetProductsByCreatorID(db, in.ID, in.Pagination)
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		products, pagination, err := stores.Products.GetProductsByCreatorID(in.ID, in.Pagination)
 		if err != nil {
 			err = errors.Wrap(err, "While fetching products")
 			sendError(w, http.StatusBadRequest, err, InternalError)
@@ -415,26 +627,26 @@ func GetUsersAddedProducts(db *sql.DB, logger *logrus.Logger) http.Handler {
 		}
 		bundledProducts := []Product{}
 		for _, product := range *products {
-			portions, err := models.GetProductsPortions(db, product.ID)
+			portions, err := stores.Portions.GetProductsPortions(product.ID)
 			if err != nil {
 				err = errors.Wrap(err, "While products portions")
 				sendError(w, http.StatusBadRequest, err, InternalError)
 				return
 			}
-			ratings, err := models.GetProductVotes(db, product.ID)
+			votes, err := stores.Votes.GetProductVoteSummary(product.ID, userID)
 			if err != nil {
-				err = errors.Wrap(err, "While fetching ratings for product")
+				err = errors.Wrap(err, "While fetching votes for product")
 				sendError(w, http.StatusBadRequest, err, InternalError)
 				return
 			}
 			bundledProduct := Product{
 				Product:  product,
 				Portions: portions,
-				Ratings:  ratings,
+				Votes:    votes,
 			}
 			bundledProducts = append(bundledProducts, bundledProduct)
 		}
-		acc, err := models.GetAccountById(db, in.ID)
+		acc, err := stores.Accounts.GetAccountById(in.ID)
 		if err != nil {
 			err = errors.Wrap(err, "While get account by id")
 			sendError(w, http.StatusBadRequest, err, InternalError)
@@ -446,7 +658,7 @@ func GetUsersAddedProducts(db *sql.DB, logger *logrus.Logger) http.Handler {
 	})
 }
 
-func RateProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
+func RateProduct(stores *store.Stores, logger *logrus.Logger) http.Handler {
 	const InvalidData = "Invalid request body"
 	const InternalError = "Internal error"
 
@@ -481,14 +693,14 @@ func RateProduct(db *sql.DB, logger *logrus.Logger) http.Handler {
 			sendError(w, http.StatusBadRequest, err, InvalidData)
 			return
 		}
-		if in.Vote == models.UpVote || in.Vote == models.DownVote || in.Vote == models.None {
+		if in.Vote.Valid() {
 			userID, ok := r.Context().Value(middleware.UserID).(int)
 			if !ok {
 				err = errors.Wrap(err, "While getting UserID from request context")
 				sendError(w, http.StatusBadRequest, err, InternalError)
 				return
 			}
-			err = models.RateProduct(db, userID, in.ID, in.Vote)
+			err = stores.Votes.RateProduct(userID, in.ID, in.Vote)
 			if err != nil {
 				sendError(w, http.StatusBadRequest, err, InternalError)
 				return
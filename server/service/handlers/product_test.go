@@ -0,0 +1,153 @@
+package handlers
+
+import (
+	"app/service/auth"
+	"app/service/models"
+	"app/service/store"
+	"net/http"
+	"testing"
+)
+
+func TestCreateProduct(t *testing.T) {
+	stores := store.NewMemory()
+	handler := CreateProduct(stores, newTestLogger())
+	userID := createTestAccount(t, stores, "user@example.com", auth.User)
+
+	type response struct {
+		Error   string `json:"error"`
+		Product struct {
+			models.Product
+			Portions []models.Portion `json:"portions"`
+			Barcodes []string         `json:"barcodes"`
+		} `json:"product"`
+	}
+	product := func(name string, portions []models.Portion, barcodes ...string) map[string]interface{} {
+		return map[string]interface{}{
+			"product": map[string]interface{}{"name": name, "portions": portions, "barcodes": barcodes},
+		}
+	}
+	portions := []models.Portion{{Unit: "100g", Nutrition: models.Nutrition{Energy: 52, Carbohydrate: 14}}}
+
+	out := response{}
+	status := serve(t, handler, userID, product("Apple", portions, "5901234123457"), &out)
+	if status != http.StatusOK {
+		t.Fatalf("Expected product to be created, got %d %q", status, out.Error)
+	}
+	if out.Product.Creator != userID || len(out.Product.Portions) != 1 {
+		t.Fatalf("Expected product created by %d with one portion, got %+v", userID, out.Product)
+	}
+	if portion := out.Product.Portions[0]; portion.Amount != 100 || portion.BaseUnit != models.BaseGram {
+		t.Errorf("Expected base amount to be parsed from unit, got %v %q", portion.Amount, portion.BaseUnit)
+	}
+
+	cases := []struct {
+		name    string
+		body    map[string]interface{}
+		message string
+	}{
+		{"same name", product("apple", portions), "Product with same name already exists"},
+		{"no portions", product("Pear", []models.Portion{}), "Entered too few portions"},
+		{"invalid portion", product("Pear", []models.Portion{{Unit: " "}}), "Invalid portion"},
+		{"invalid barcode", product("Pear", portions, "123"), "Invalid barcode"},
+		{"taken barcode", product("Pear", portions, "5901234123457"), "Barcode is already assigned to another product"},
+	}
+	for _, c := range cases {
+		out := response{}
+		status := serve(t, handler, userID, c.body, &out)
+		if status != http.StatusBadRequest || out.Error != c.message {
+			t.Errorf("%s: expected %q, got %d %q", c.name, c.message, status, out.Error)
+		}
+	}
+}
+
+func TestRateProduct(t *testing.T) {
+	stores := store.NewMemory()
+	handler := RateProduct(stores, newTestLogger())
+	userID := createTestAccount(t, stores, "user@example.com", auth.User)
+	otherID := createTestAccount(t, stores, "other@example.com", auth.User)
+	product, _ := createTestProduct(t, stores, userID, "Apple", "piece")
+
+	type response struct {
+		Error string `json:"error"`
+	}
+	rate := func(userID int, vote models.Vote) int {
+		out := response{}
+		return serve(t, handler, userID, map[string]interface{}{"id": product.ID, "vote": vote}, &out)
+	}
+	summary := func(userID int) *models.VoteSummary {
+		summary, err := stores.Votes.GetProductVoteSummary(product.ID, userID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return summary
+	}
+
+	if status := rate(userID, models.UpVote); status != http.StatusOK {
+		t.Fatalf("Expected vote to be stored, got %d", status)
+	}
+	if status := rate(otherID, models.DownVote); status != http.StatusOK {
+		t.Fatalf("Expected vote to be stored, got %d", status)
+	}
+	if status := rate(userID, models.DownVote); status != http.StatusOK {
+		t.Fatalf("Expected vote to be changed, got %d", status)
+	}
+	if got := summary(userID); got.Count != 2 || got.Net != -2 || got.UserVote != models.DownVote {
+		t.Errorf("Expected two down votes, got %+v", got)
+	}
+	if status := rate(userID, models.None); status != http.StatusOK {
+		t.Fatalf("Expected vote to be withdrawn, got %d", status)
+	}
+	if got := summary(userID); got.Count != 1 || got.UserVote != models.None {
+		t.Errorf("Expected only the other vote to be left, got %+v", got)
+	}
+	if status := rate(userID, models.Vote(2)); status != http.StatusBadRequest {
+		t.Errorf("Expected invalid vote to be refused, got %d", status)
+	}
+}
+
+func TestUpdateProduct(t *testing.T) {
+	stores := store.NewMemory()
+	handler := UpdateProduct(stores, newTestLogger())
+	moderatorID := createTestAccount(t, stores, "moderator@example.com", auth.Moderator)
+	apple, _ := createTestProduct(t, stores, moderatorID, "Apple", "piece")
+	createTestProduct(t, stores, moderatorID, "Pear", "piece")
+	deleted, _ := createTestProduct(t, stores, moderatorID, "Plum", "piece")
+	err := stores.Products.DeleteProduct(deleted.ID, moderatorID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type response struct {
+		Error string `json:"error"`
+	}
+	rename := func(id int, name string) map[string]interface{} {
+		return map[string]interface{}{"id": id, "product": map[string]interface{}{"name": name}}
+	}
+	cases := []struct {
+		name    string
+		body    map[string]interface{}
+		status  int
+		message string
+	}{
+		{"same name", rename(apple.ID, " apple "), http.StatusOK, ""},
+		{"taken name", rename(apple.ID, "PEAR"), http.StatusBadRequest, "Product with same name already exists"},
+		{"blank name", rename(apple.ID, "  "), http.StatusBadRequest, "Product name cannot be empty"},
+		{"deleted product", rename(deleted.ID, "Damson"), http.StatusBadRequest, "Deleted product has to be restored before it is changed"},
+		{"missing product", rename(-1, "Damson"), http.StatusBadRequest, "No product with that id"},
+		{"new name", rename(apple.ID, "  Green Apple"), http.StatusOK, ""},
+	}
+	for _, c := range cases {
+		out := response{}
+		status := serve(t, handler, moderatorID, c.body, &out)
+		if status != c.status || out.Error != c.message {
+			t.Errorf("%s: expected %d %q, got %d %q", c.name, c.status, c.message, status, out.Error)
+		}
+	}
+	product, err := stores.Products.GetProductById(apple.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if product.Name != "green apple" {
+		t.Errorf("Expected trimmed lowercase name, got %q", product.Name)
+	}
+}
@@ -0,0 +1,318 @@
+package handlers
+
+import (
+	"app/service/middleware"
+	"app/service/models"
+	"app/service/store"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func ProposeProductChange(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InvalidProduct = "Product does not exist"
+	const NoChanges = "Proposal has to change name, description or portions"
+	const InvalidName = "Name can't be empty"
+	const NameExists = "Product with same name already exists"
+	const RecipePortions = "Portions of a recipe are computed from its ingredients"
+	const InvalidPortion = "Proposed portion does not belong to the product"
+	const InvalidUnit = "Portion unit can't be empty"
+	const InvalidNutrition = "Invalid nutrient values"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+		ID          int              `json:"id"`
+		Name        *string          `json:"name,omitempty"`
+		Description *string          `json:"description,omitempty"`
+		Portions    []models.Portion `json:"portions,omitempty"`
+		Comment     string           `json:"comment,omitempty"`
+	}
+	type ResponseObject struct {
+		Error    string           `json:"error,omitempty"`
+		Proposal *models.Proposal `json:"proposal,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While proposing product change")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, proposal *models.Proposal) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Proposal: proposal,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		product, err := stores.Products.GetProductById(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db product")
+			sendError(w, http.StatusBadRequest, err, InvalidProduct)
+			return
+		}
+		portions, err := stores.Portions.GetProductsPortions(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db product portions")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		if in.Name != nil {
+			name := strings.ToLower(strings.TrimSpace(*in.Name))
+			if name == "" {
+				err = errors.New("Empty name")
+				sendError(w, http.StatusBadRequest, err, InvalidName)
+				return
+			}
+			if name != product.Name {
+				exists, err := stores.Products.ProductNameExists(name)
+				if err != nil {
+					sendError(w, http.StatusBadRequest, err, InternalError)
+					return
+				}
+				if exists {
+					err = errors.New("Name taken")
+					sendError(w, http.StatusBadRequest, err, NameExists)
+					return
+				}
+			}
+			in.Name = &name
+		}
+		if len(in.Portions) > 0 && product.Recipe {
+			err = errors.New("Portions proposed for recipe")
+			sendError(w, http.StatusBadRequest, err, RecipePortions)
+			return
+		}
+		productsPortions := map[int]bool{}
+		for _, portion := range portions {
+			productsPortions[portion.ID] = true
+		}
+		for _, portion := range in.Portions {
+			if portion.ID != 0 && !productsPortions[portion.ID] {
+				err = errors.New("Portion of another product")
+				sendError(w, http.StatusBadRequest, err, InvalidPortion)
+				return
+			}
+			if strings.TrimSpace(portion.Unit) == "" {
+				err = errors.New("Empty portion unit")
+				sendError(w, http.StatusBadRequest, err, InvalidUnit)
+				return
+			}
+			err = portion.Nutrition.Validate()
+			if err != nil {
+				err = errors.Wrap(err, "While validating portion nutrition")
+				sendError(w, http.StatusBadRequest, err, InvalidNutrition)
+				return
+			}
+		}
+		proposal := models.Proposal{
+			ProductID:   in.ID,
+			UserID:      userID,
+			Name:        in.Name,
+			Description: in.Description,
+			Portions:    in.Portions,
+			Comment:     in.Comment,
+		}
+		if len(models.DiffProposal(*product, portions, proposal)) == 0 {
+			err = errors.New("Empty proposal")
+			sendError(w, http.StatusBadRequest, err, NoChanges)
+			return
+		}
+		dbProposal, err := stores.Proposals.CreateProposal(proposal)
+		if err != nil {
+			err = errors.Wrap(err, "While creating db proposal")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, dbProposal)
+		return
+	})
+}
+
+func GetProposals(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+
+	type Product struct {
+		*models.Product
+		Portions []models.Portion `json:"portions"`
+	}
+	type Proposal struct {
+		models.Proposal
+		Product Product                 `json:"product"`
+		Changes []models.ProposalChange `json:"changes"`
+	}
+	type RequestObject struct {
+		Pagination models.Pagination `json:"pagination"`
+	}
+	type ResponseObject struct {
+		Error      string            `json:"error,omitempty"`
+		Proposals  []Proposal        `json:"proposals,omitempty"`
+		Pagination models.Pagination `json:"pagination"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While getting proposals")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, proposals []Proposal, pagination models.Pagination) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Proposals:  proposals,
+			Pagination: pagination,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		proposals, pagination, err := stores.Proposals.GetPendingProposals(in.Pagination)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db proposals")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		bundled := []Proposal{}
+		for _, proposal := range *proposals {
+			product, err := stores.Products.GetProductById(proposal.ProductID)
+			if err != nil {
+				err = errors.Wrap(err, "While getting db product")
+				sendError(w, http.StatusBadRequest, err, InternalError)
+				return
+			}
+			portions, err := stores.Portions.GetProductsPortions(proposal.ProductID)
+			if err != nil {
+				err = errors.Wrap(err, "While getting db product portions")
+				sendError(w, http.StatusBadRequest, err, InternalError)
+				return
+			}
+			bundled = append(bundled, Proposal{
+				Proposal: proposal,
+				Product:  Product{Product: product, Portions: portions},
+				Changes:  models.DiffProposal(*product, portions, proposal),
+			})
+		}
+		sendData(w, http.StatusOK, bundled, *pagination)
+		return
+	})
+}
+
+func ApproveProposal(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	return reviewProposal(stores, logger, true)
+}
+
+func RejectProposal(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	return reviewProposal(stores, logger, false)
+}
+
+// reviewProposal approves or rejects pending proposal, approved changes are applied to the product
+func reviewProposal(stores *store.Stores, logger *logrus.Logger, approve bool) http.Handler {
+	const InvalidData = "Invalid request body"
+	const NotFound = "Proposal does not exist"
+	const AlreadyReviewed = "Proposal is already reviewed"
+	const NameExists = "Product with same name already exists"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+		ID   int    `json:"id"`
+		Note string `json:"note,omitempty"`
+	}
+	type ResponseObject struct {
+		Error    string           `json:"error,omitempty"`
+		Proposal *models.Proposal `json:"proposal,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While reviewing proposal")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, proposal *models.Proposal) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Proposal: proposal,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		proposal, err := stores.Proposals.GetProposal(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db proposal")
+			sendError(w, http.StatusBadRequest, err, NotFound)
+			return
+		}
+		if proposal.Status != models.ProposalPending {
+			err = errors.New(AlreadyReviewed)
+			sendError(w, http.StatusBadRequest, err, AlreadyReviewed)
+			return
+		}
+		var reviewed *models.Proposal
+		if approve {
+			reviewed, err = stores.Proposals.ApproveProposal(in.ID, userID, in.Note)
+		} else {
+			reviewed, err = stores.Proposals.RejectProposal(in.ID, userID, in.Note)
+		}
+		if err != nil {
+			err = errors.Wrap(err, "While reviewing db proposal")
+			if errors.Cause(err) == models.ErrProductExists {
+				sendError(w, http.StatusBadRequest, err, NameExists)
+				return
+			}
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, reviewed)
+		return
+	})
+}
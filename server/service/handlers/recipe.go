@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"app/service/middleware"
+	"app/service/models"
+	"app/service/store"
+	"encoding/json"
+	"net/http"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func CreateRecipe(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+	const AlreadyExists = "Product with same name already exists"
+	const EmptyName = "Product name cannot be empty"
+	const InvalidYield = "Recipe yield has to be positive"
+	const TooManyIngredients = "Entered too many ingredients"
+	const TooFewIngredients = "Entered too few ingredients"
+	const InvalidIngredient = "Invalid ingredient"
+	const InvalidProduct = "Ingredient product does not exist or was deleted"
+	const maxIngredients = 50
+
+	type Recipe struct {
+		Name        string              `json:"name"`
+		Description string              `json:"description"`
+		Yield       float64             `json:"yield"`
+		Ingredients []models.Ingredient `json:"ingredients"`
+	}
+	type Product struct {
+		*models.Product
+		Portions    []models.Portion    `json:"portions"`
+		Ingredients []models.Ingredient `json:"ingredients"`
+	}
+	type RequestObject struct {
+		Recipe *Recipe `json:"recipe"`
+	}
+	type ResponseObject struct {
+		Error   string   `json:"error,omitempty"`
+		Product *Product `json:"product,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While creating recipe")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, product *Product) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Product: product,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if in.Recipe == nil {
+			err = errors.New("No recipe provided")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		recipe := in.Recipe
+		if recipe.Yield <= 0 {
+			err = errors.New(InvalidYield)
+			sendError(w, http.StatusBadRequest, err, InvalidYield)
+			return
+		}
+		if len(recipe.Ingredients) > maxIngredients {
+			err = errors.New(TooManyIngredients)
+			sendError(w, http.StatusBadRequest, err, TooManyIngredients)
+			return
+		}
+		if len(recipe.Ingredients) == 0 {
+			err = errors.New(TooFewIngredients)
+			sendError(w, http.StatusBadRequest, err, TooFewIngredients)
+			return
+		}
+		for _, ingredient := range recipe.Ingredients {
+			if ingredient.Quantity <= 0 {
+				err = errors.New("Non positive ingredient quantity")
+				sendError(w, http.StatusBadRequest, err, InvalidIngredient)
+				return
+			}
+			portion, err := stores.Portions.GetPortion(ingredient.PortionID)
+			if err != nil {
+				err = errors.Wrap(err, "While getting ingredient portion")
+				sendError(w, http.StatusBadRequest, err, InvalidIngredient)
+				return
+			}
+			if portion.ProductID != ingredient.ProductID {
+				err = errors.New("Ingredient portion do not belong to ingredient product")
+				sendError(w, http.StatusBadRequest, err, InvalidIngredient)
+				return
+			}
+			product, err := stores.Products.GetProductById(ingredient.ProductID)
+			if err != nil && errors.Cause(err) != models.ErrNotFound {
+				err = errors.Wrap(err, "While getting ingredient product")
+				sendError(w, http.StatusBadRequest, err, InternalError)
+				return
+			}
+			if product == nil || product.Deleted {
+				err = errors.New(InvalidProduct)
+				sendError(w, http.StatusBadRequest, err, InvalidProduct)
+				return
+			}
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		newProduct := models.Product{
+			Creator:     userID,
+			Name:        recipe.Name,
+			Description: recipe.Description,
+			Recipe:      true,
+			Yield:       recipe.Yield,
+		}
+		dbProduct, dbIngredients, err := stores.Recipes.CreateRecipe(newProduct, recipe.Ingredients)
+		if err != nil {
+			if errors.Cause(err) == models.ErrProductExists {
+				sendError(w, http.StatusBadRequest, err, AlreadyExists)
+				return
+			}
+			if errors.Cause(err) == models.ErrEmptyProductName {
+				sendError(w, http.StatusBadRequest, err, EmptyName)
+				return
+			}
+			if errors.Cause(err) == models.ErrDeletedIngredient {
+				sendError(w, http.StatusBadRequest, err, InvalidProduct)
+				return
+			}
+			if pgerr, ok := errors.Cause(err).(*pq.Error); ok {
+				if pgerr.Code == "23505" {
+					sendError(w, http.StatusBadRequest, err, AlreadyExists)
+					return
+				}
+			}
+			err = errors.Wrap(err, "While creating recipe")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		portions, err := stores.Portions.GetProductsPortions(dbProduct.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While fetching recipe portions")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, &Product{Product: dbProduct, Portions: portions, Ingredients: dbIngredients})
+		return
+	})
+}
+
+func GetRecipe(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+	const NotRecipe = "Product is not a recipe"
+
+	type Ingredient struct {
+		models.Ingredient
+		Product *models.Product `json:"product"`
+		Portion *models.Portion `json:"portion"`
+	}
+	type Product struct {
+		*models.Product
+		Portions    []models.Portion `json:"portions"`
+		Ingredients []Ingredient     `json:"ingredients"`
+	}
+	type RequestObject struct {
+		ID int `json:"id"`
+	}
+	type ResponseObject struct {
+		Error   string   `json:"error,omitempty"`
+		Product *Product `json:"product,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While getting recipe")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, product *Product) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Product: product,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		product, err := stores.Products.GetProductById(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While fetching product")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		if !product.Recipe {
+			err = errors.New(NotRecipe)
+			sendError(w, http.StatusBadRequest, err, NotRecipe)
+			return
+		}
+		portions, err := stores.Portions.GetProductsPortions(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While fetching recipe portions")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		ingredients, err := stores.Recipes.GetRecipesIngredients(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While fetching recipe ingredients")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		popIngredients := []Ingredient{}
+		for _, ingredient := range ingredients {
+			ingProduct, err := stores.Products.GetProductById(ingredient.ProductID)
+			if err != nil {
+				err = errors.Wrap(err, "While fetching ingredient product")
+				sendError(w, http.StatusBadRequest, err, InternalError)
+				return
+			}
+			ingPortion, err := stores.Portions.GetPortion(ingredient.PortionID)
+			if err != nil {
+				err = errors.Wrap(err, "While fetching ingredient portion")
+				sendError(w, http.StatusBadRequest, err, InternalError)
+				return
+			}
+			popIngredients = append(popIngredients, Ingredient{
+				Ingredient: ingredient,
+				Product:    ingProduct,
+				Portion:    ingPortion,
+			})
+		}
+		sendData(w, http.StatusOK, &Product{Product: product, Portions: portions, Ingredients: popIngredients})
+		return
+	})
+}
@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"app/service/auth"
+	"app/service/models"
+	"app/service/store"
+	"net/http"
+	"testing"
+)
+
+func TestCreateRecipe(t *testing.T) {
+	stores := store.NewMemory()
+	handler := CreateRecipe(stores, newTestLogger())
+	userID := createTestAccount(t, stores, "user@example.com", auth.User)
+	apple, applePortions := createTestProduct(t, stores, userID, "Apple", "100g")
+	pear, pearPortions := createTestProduct(t, stores, userID, "Pear", "100g")
+	err := stores.Products.DeleteProduct(pear.ID, userID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	type response struct {
+		Error   string `json:"error"`
+		Product *struct {
+			models.Product
+			Portions []models.Portion `json:"portions"`
+		} `json:"product"`
+	}
+	recipe := func(name string, ingredients ...models.Ingredient) map[string]interface{} {
+		return map[string]interface{}{
+			"recipe": map[string]interface{}{"name": name, "yield": 2, "ingredients": ingredients},
+		}
+	}
+
+	out := response{}
+	status := serve(t, handler, userID, recipe("Apple pie", models.Ingredient{ProductID: apple.ID, PortionID: applePortions[0].ID, Quantity: 3}), &out)
+	if status != http.StatusOK {
+		t.Fatalf("Expected recipe to be created, got %d %q", status, out.Error)
+	}
+	if !out.Product.Recipe || len(out.Product.Portions) != 2 {
+		t.Errorf("Expected recipe with derived portions, got %+v", out.Product)
+	}
+
+	out = response{}
+	status = serve(t, handler, userID, recipe("Pear pie", models.Ingredient{ProductID: pear.ID, PortionID: pearPortions[0].ID, Quantity: 3}), &out)
+	if status != http.StatusBadRequest || out.Error != "Ingredient product does not exist or was deleted" {
+		t.Errorf("Expected deleted ingredient product to be refused, got %d %q", status, out.Error)
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"app/service/middleware"
+	"app/service/models"
+	"app/service/store"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func GetRevisions(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+		ID         int               `json:"id"`
+		Pagination models.Pagination `json:"pagination"`
+	}
+	type ResponseObject struct {
+		Error      string             `json:"error,omitempty"`
+		Revisions  *[]models.Revision `json:"revisions,omitempty"`
+		Pagination models.Pagination  `json:"pagination"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While getting revisions")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, revisions *[]models.Revision, pagination models.Pagination) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Revisions:  revisions,
+			Pagination: pagination,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		revisions, pagination, err := stores.Revisions.GetProductsRevisions(in.ID, in.Pagination)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db revisions")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, revisions, *pagination)
+		return
+	})
+}
+
+func RollbackProduct(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const NotFound = "Revision does not exist"
+	const Deleted = "Deleted product has to be restored before it is rolled back"
+	const AlreadyExists = "Product with same name already exists"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+		RevisionID int `json:"revisionID"`
+	}
+	// SkippedPortions are portions of the revision which were moved to another product, e.g. by a merge
+	type ResponseObject struct {
+		Error           string           `json:"error,omitempty"`
+		Revision        *models.Revision `json:"revision,omitempty"`
+		SkippedPortions []models.Portion `json:"skippedPortions,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While rolling back product")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, revision *models.Revision, skipped []models.Portion) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Revision:        revision,
+			SkippedPortions: skipped,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		_, err = stores.Revisions.GetRevision(in.RevisionID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db revision")
+			sendError(w, http.StatusBadRequest, err, NotFound)
+			return
+		}
+		revision, skipped, err := stores.Revisions.RollbackProduct(in.RevisionID, userID)
+		if err != nil {
+			err = errors.Wrap(err, "While rolling back db product")
+			if errors.Cause(err) == models.ErrProductDeleted {
+				sendError(w, http.StatusBadRequest, err, Deleted)
+				return
+			}
+			if errors.Cause(err) == models.ErrProductExists {
+				sendError(w, http.StatusBadRequest, err, AlreadyExists)
+				return
+			}
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, revision, skipped)
+		return
+	})
+}
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"app/service/middleware"
+	"app/service/models"
+	"app/service/store"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func CreateSavedMeal(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+	const AlreadyExists = "Saved meal with same name already exists"
+	const MissingName = "Saved meal name cannot be empty"
+	const TooManyItems = "Entered too many items"
+	const TooFewItems = "Entered too few items"
+	const InvalidItem = "Invalid item"
+	const maxItems = 20
+
+	type RequestObject struct {
+		Meal *models.SavedMeal `json:"meal"`
+	}
+	type ResponseObject struct {
+		Error string            `json:"error,omitempty"`
+		Meal  *models.SavedMeal `json:"meal,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While creating saved meal")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, meal *models.SavedMeal) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Meal: meal,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if in.Meal == nil {
+			err = errors.New("No saved meal provided")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		meal := in.Meal
+		if strings.TrimSpace(meal.Name) == "" {
+			err = errors.New(MissingName)
+			sendError(w, http.StatusBadRequest, err, MissingName)
+			return
+		}
+		if len(meal.Items) > maxItems {
+			err = errors.New(TooManyItems)
+			sendError(w, http.StatusBadRequest, err, TooManyItems)
+			return
+		}
+		if len(meal.Items) == 0 {
+			err = errors.New(TooFewItems)
+			sendError(w, http.StatusBadRequest, err, TooFewItems)
+			return
+		}
+		for _, item := range meal.Items {
+			if item.Quantity <= 0 {
+				err = errors.New("Non positive item quantity")
+				sendError(w, http.StatusBadRequest, err, InvalidItem)
+				return
+			}
+			portion, err := stores.Portions.GetPortion(item.PortionID)
+			if err != nil {
+				err = errors.Wrap(err, "While getting item portion")
+				sendError(w, http.StatusBadRequest, err, InvalidItem)
+				return
+			}
+			if portion.ProductID != item.ProductID {
+				err = errors.New("Item portion do not belong to item product")
+				sendError(w, http.StatusBadRequest, err, InvalidItem)
+				return
+			}
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		meal.UserID = userID
+		dbMeal, err := stores.SavedMeals.CreateSavedMeal(*meal)
+		if err != nil {
+			if pgerr, ok := err.(*pq.Error); ok {
+				if pgerr.Code == "23505" {
+					sendError(w, http.StatusBadRequest, err, AlreadyExists)
+					return
+				}
+			}
+			err = errors.Wrap(err, "While creating db saved meal")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, dbMeal)
+		return
+	})
+}
+
+func GetSavedMeals(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+	}
+	type ResponseObject struct {
+		Error string              `json:"error,omitempty"`
+		Meals *[]models.SavedMeal `json:"meals,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While getting saved meals")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, meals *[]models.SavedMeal) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Meals: meals,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		meals, err := stores.SavedMeals.GetUsersSavedMeals(userID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db saved meals")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, &meals)
+		return
+	})
+}
+
+func DeleteSavedMeal(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+	const PermissionDenied = "Permission denied"
+
+	type RequestObject struct {
+		ID int `json:"id"`
+	}
+	type ResponseObject struct {
+		Error string `json:"error,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While deleting saved meal")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		meal, err := stores.SavedMeals.GetSavedMeal(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db saved meal")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if meal.UserID != userID {
+			err = errors.New("Permission denied, user id do not match")
+			sendError(w, http.StatusUnauthorized, err, PermissionDenied)
+			return
+		}
+		err = stores.SavedMeals.DeleteSavedMeal(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While deleting db saved meal")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK)
+		return
+	})
+}
+
+func LogSavedMeal(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InvalidSlot = "Unknown meal slot"
+	const InternalError = "Internal error"
+	const PermissionDenied = "Permission denied"
+	const InvalidEntries = "Some of the entries are no longer valid"
+
+	type RequestObject struct {
+		ID   int       `json:"id"`
+		Date time.Time `json:"date"`
+		Slot string    `json:"slot"`
+	}
+	type ResponseObject struct {
+		Error   string              `json:"error,omitempty"`
+		Fields  []models.FieldError `json:"fields,omitempty"`
+		Entries *[]models.Entry     `json:"entries,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While logging saved meal")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		if invalid, ok := errors.Cause(err).(*models.ValidationError); ok {
+			out.Fields = invalid.Fields
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, entries *[]models.Entry) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Entries: entries,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		meal, err := stores.SavedMeals.GetSavedMeal(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db saved meal")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if meal.UserID != userID {
+			err = errors.New("Permission denied, user id do not match")
+			sendError(w, http.StatusUnauthorized, err, PermissionDenied)
+			return
+		}
+		slot := models.NormalizeSlot(in.Slot)
+		slots, err := stores.Entries.GetUsersSlots(userID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db meal slots")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		if !models.HasSlot(slots, slot) {
+			err = errors.New("Slot is not one of users slots")
+			sendError(w, http.StatusBadRequest, err, InvalidSlot)
+			return
+		}
+		date := in.Date
+		if date.IsZero() {
+			date = time.Now()
+		}
+		entries, err := stores.SavedMeals.LogSavedMeal(*meal, userID, models.Day(date), slot)
+		if _, ok := errors.Cause(err).(*models.ValidationError); ok {
+			sendError(w, http.StatusBadRequest, err, InvalidEntries)
+			return
+		}
+		if err != nil {
+			err = errors.Wrap(err, "While creating db entries")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, &entries)
+		return
+	})
+}
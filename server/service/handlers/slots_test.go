@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"app/service/auth"
+	"app/service/models"
+	"app/service/store"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSetSlots(t *testing.T) {
+	stores := store.NewMemory()
+	logger := newTestLogger()
+	userID := createTestAccount(t, stores, "user@example.com", auth.User)
+	otherID := createTestAccount(t, stores, "other@example.com", auth.User)
+	product, portions := createTestProduct(t, stores, userID, "Apple", "piece")
+
+	type response struct {
+		Error string    `json:"error"`
+		Slots *[]string `json:"slots"`
+	}
+	out := response{}
+	status := serve(t, SetSlots(stores, logger), userID, map[string]interface{}{"slots": []string{" Brunch ", "Supper"}}, &out)
+	if status != http.StatusOK {
+		t.Fatalf("Expected slots to be set, got %d %q", status, out.Error)
+	}
+	out = response{}
+	serve(t, GetSlots(stores, logger), userID, map[string]interface{}{}, &out)
+	if out.Slots == nil || len(*out.Slots) != 2 || (*out.Slots)[0] != "brunch" || (*out.Slots)[1] != "supper" {
+		t.Errorf("Expected normalized slots of the user, got %v", out.Slots)
+	}
+	out = response{}
+	serve(t, GetSlots(stores, logger), otherID, map[string]interface{}{}, &out)
+	if out.Slots == nil || len(*out.Slots) != len(models.DefaultSlots) {
+		t.Errorf("Expected other user to keep default slots, got %v", out.Slots)
+	}
+
+	entry := func(slot string) map[string]interface{} {
+		return map[string]interface{}{"entry": models.Entry{
+			ProductID: product.ID,
+			PortionID: portions[0].ID,
+			Quantity:  1,
+			Date:      time.Now(),
+			Slot:      slot,
+		}}
+	}
+	created := entryResponse{}
+	status = serve(t, CreateEntry(stores, logger), userID, entry("brunch"), &created)
+	if status != http.StatusOK {
+		t.Errorf("Expected entry in users own slot, got %d %q %v", status, created.Error, created.Fields)
+	}
+	created = entryResponse{}
+	status = serve(t, CreateEntry(stores, logger), userID, entry("lunch"), &created)
+	if status == http.StatusOK || !sameFields(created.Fields, []string{"slot"}) {
+		t.Errorf("Expected default slot to be rejected once slots are set, got %d %v", status, created.Fields)
+	}
+}
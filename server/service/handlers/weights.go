@@ -0,0 +1,442 @@
+package handlers
+
+import (
+	"app/service/middleware"
+	"app/service/models"
+	"app/service/store"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+func validateWeight(weight *models.Weight) error {
+	if !weight.Unit.Valid() {
+		return errors.New("Unknown weight unit")
+	}
+	if weight.Weight <= 0 {
+		return errors.New("Non positive weight")
+	}
+	if weight.Date.IsZero() {
+		weight.Date = time.Now()
+	}
+	weight.Date = models.Day(weight.Date)
+	return nil
+}
+
+func CreateWeight(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InvalidWeight = "Weight has to be positive and given in kg or lb"
+	const AlreadyExists = "Weight for that date already exists"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+		Weight *models.Weight `json:"weight"`
+	}
+	type ResponseObject struct {
+		Error  string         `json:"error,omitempty"`
+		Weight *models.Weight `json:"weight,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While creating weight")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, weight *models.Weight) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Weight: weight,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if in.Weight == nil {
+			err = errors.New("No weight provided")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		err = validateWeight(in.Weight)
+		if err != nil {
+			err = errors.Wrap(err, "While validating weight")
+			sendError(w, http.StatusBadRequest, err, InvalidWeight)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		in.Weight.UserID = userID
+		dbWeight, err := stores.Weights.CreateWeight(*in.Weight)
+		if err != nil {
+			if pgerr, ok := err.(*pq.Error); ok {
+				if pgerr.Code == "23505" {
+					sendError(w, http.StatusBadRequest, err, AlreadyExists)
+					return
+				}
+			}
+			err = errors.Wrap(err, "While creating db weight")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK, dbWeight)
+		return
+	})
+}
+
+func GetWeights(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InvalidRange = "Invalid date range"
+	const InvalidUnit = "Unknown weight unit"
+	const InternalError = "Internal error"
+
+	type RequestObject struct {
+		From time.Time         `json:"from"`
+		To   time.Time         `json:"to"`
+		Unit models.WeightUnit `json:"unit"`
+	}
+	type ResponseObject struct {
+		Error      string               `json:"error,omitempty"`
+		Weights    *[]models.Weight     `json:"weights,omitempty"`
+		Trend      *[]models.TrendPoint `json:"trend,omitempty"`
+		WeeklyRate float64              `json:"weeklyRate"`
+		Unit       models.WeightUnit    `json:"unit,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While getting weights")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, weights *[]models.Weight, trend *[]models.TrendPoint, weeklyRate float64, unit models.WeightUnit) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Weights:    weights,
+			Trend:      trend,
+			WeeklyRate: weeklyRate,
+			Unit:       unit,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if in.Unit == "" {
+			in.Unit = models.Kilograms
+		}
+		if !in.Unit.Valid() {
+			err = errors.New(InvalidUnit)
+			sendError(w, http.StatusBadRequest, err, InvalidUnit)
+			return
+		}
+		if in.To.IsZero() {
+			in.To = time.Now()
+		}
+		from, to := models.Day(in.From), models.Day(in.To)
+		if to.Before(from) {
+			err = errors.New("Date range is reversed")
+			sendError(w, http.StatusBadRequest, err, InvalidRange)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		// trend is smoothed over the whole history, so it does not restart at the beginning of the range
+		history, err := stores.Weights.GetUsersWeights(userID, time.Time{}, to)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db weights")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		points := models.WeightTrend(history)
+		weights := []models.Weight{}
+		for _, weight := range history {
+			if !models.Day(weight.Date).Before(from) {
+				weights = append(weights, weight)
+			}
+		}
+		trend := []models.TrendPoint{}
+		for _, point := range points {
+			if point.Date.Before(from) {
+				continue
+			}
+			trend = append(trend, models.TrendPoint{
+				Date:   point.Date,
+				Weight: in.Unit.FromKilograms(point.Weight),
+				Trend:  in.Unit.FromKilograms(point.Trend),
+			})
+		}
+		weeklyRate := in.Unit.FromKilograms(models.WeeklyRate(points))
+		sendData(w, http.StatusOK, &weights, &trend, weeklyRate, in.Unit)
+		return
+	})
+}
+
+func UpdateWeight(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InvalidWeight = "Weight has to be positive and given in kg or lb"
+	const AlreadyExists = "Weight for that date already exists"
+	const InternalError = "Internal error"
+	const PermissionDenied = "Permission denied"
+
+	type RequestObject struct {
+		ID     int            `json:"id"`
+		Weight *models.Weight `json:"weight"`
+	}
+	type ResponseObject struct {
+		Error string `json:"error,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While updating weight")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if in.Weight == nil {
+			err = errors.New("No weight provided")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		err = validateWeight(in.Weight)
+		if err != nil {
+			err = errors.Wrap(err, "While validating weight")
+			sendError(w, http.StatusBadRequest, err, InvalidWeight)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		weight, err := stores.Weights.GetWeight(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db weight")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if weight.UserID != userID {
+			err = errors.New("Permission denied, user id do not match")
+			sendError(w, http.StatusUnauthorized, err, PermissionDenied)
+			return
+		}
+		err = stores.Weights.UpdateWeight(in.ID, *in.Weight)
+		if err != nil {
+			if pgerr, ok := errors.Cause(err).(*pq.Error); ok {
+				if pgerr.Code == "23505" {
+					sendError(w, http.StatusBadRequest, err, AlreadyExists)
+					return
+				}
+			}
+			err = errors.Wrap(err, "While updating db weight")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK)
+		return
+	})
+}
+
+func DeleteWeight(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InternalError = "Internal error"
+	const PermissionDenied = "Permission denied"
+
+	type RequestObject struct {
+		ID int `json:"id"`
+	}
+	type ResponseObject struct {
+		Error string `json:"error,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While deleting weight")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		weight, err := stores.Weights.GetWeight(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db weight")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if weight.UserID != userID {
+			err = errors.New("Permission denied, user id do not match")
+			sendError(w, http.StatusUnauthorized, err, PermissionDenied)
+			return
+		}
+		err = stores.Weights.DeleteWeight(in.ID)
+		if err != nil {
+			err = errors.Wrap(err, "While deleting db weight")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		sendData(w, http.StatusOK)
+		return
+	})
+}
+
+func GetTDEE(stores *store.Stores, logger *logrus.Logger) http.Handler {
+	const InvalidData = "Invalid request body"
+	const InvalidWindow = "Window has to be between 14 and 90 days"
+	const NotEnoughData = "Not enough entries or weight measurements to estimate expenditure"
+	const InternalError = "Internal error"
+	const defaultWindow = 28
+
+	type RequestObject struct {
+		To     time.Time `json:"to"`
+		Window int       `json:"window"`
+	}
+	type ResponseObject struct {
+		Error string       `json:"error,omitempty"`
+		TDEE  *models.TDEE `json:"tdee,omitempty"`
+	}
+	sendError := func(w http.ResponseWriter, status int, err error, message string) {
+		err = errors.Wrap(err, "While estimating tdee")
+		logger.Error(err)
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			Error: message,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	sendData := func(w http.ResponseWriter, status int, tdee *models.TDEE) {
+		w.Header().Add("Content-Type", "application/json")
+		w.WriteHeader(status)
+		out := ResponseObject{
+			TDEE: tdee,
+		}
+		json.NewEncoder(w).Encode(out)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		in := &RequestObject{}
+		err := json.NewDecoder(r.Body).Decode(in)
+		if err != nil {
+			err = errors.Wrap(err, "While decoding request body")
+			sendError(w, http.StatusBadRequest, err, InvalidData)
+			return
+		}
+		if in.Window == 0 {
+			in.Window = defaultWindow
+		}
+		if in.Window < 14 || in.Window > 90 {
+			err = errors.New(InvalidWindow)
+			sendError(w, http.StatusBadRequest, err, InvalidWindow)
+			return
+		}
+		if in.To.IsZero() {
+			in.To = time.Now()
+		}
+		to := models.Day(in.To)
+		from := to.AddDate(0, 0, -in.Window)
+		userID, ok := r.Context().Value(middleware.UserID).(int)
+		if !ok {
+			err = errors.New("While getting UserID from request context")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		dates, err := stores.Entries.GetUsersEntryDates(userID)
+		if err != nil {
+			err = errors.Wrap(err, "While fetching dates")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		summaries, err := stores.Entries.GetUsersDailySummaries(userID, from, to)
+		if err != nil {
+			err = errors.Wrap(err, "While fetching daily summaries")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		intake := map[time.Time]float64{}
+		for _, summary := range summaries {
+			intake[models.Day(summary.Date)] = summary.Total.Energy
+		}
+		history, err := stores.Weights.GetUsersWeights(userID, time.Time{}, to)
+		if err != nil {
+			err = errors.Wrap(err, "While getting db weights")
+			sendError(w, http.StatusBadRequest, err, InternalError)
+			return
+		}
+		tdee, err := models.EstimateTDEE(intake, *dates, models.WeightTrend(history), from, to)
+		if err != nil {
+			err = errors.Wrap(err, "While estimating")
+			sendError(w, http.StatusBadRequest, err, NotEnoughData)
+			return
+		}
+		sendData(w, http.StatusOK, tdee)
+		return
+	})
+}
@@ -0,0 +1,162 @@
+package importer
+
+import (
+	"app/service/models"
+	"database/sql"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/sirupsen/logrus"
+)
+
+// State is saved to the state file after every batch, so interrupted import can resume where it stopped
+type State struct {
+	Source    string `json:"source"`
+	Processed int    `json:"processed"`
+	Imported  int    `json:"imported"`
+	Skipped   int    `json:"skipped"`
+}
+
+func loadState(path, source string) (*State, error) {
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return &State{Source: source}, nil
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "While reading state file")
+	}
+	state := &State{}
+	err = json.Unmarshal(data, state)
+	if err != nil {
+		return nil, errors.Wrap(err, "While parsing state file")
+	}
+	if state.Source != source {
+		return nil, errors.New("State file belongs to another dump, remove it to start over")
+	}
+	return state, nil
+}
+
+func saveState(path string, state *State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	err = ioutil.WriteFile(tmp, data, 0644)
+	if err != nil {
+		return errors.Wrap(err, "While writing state file")
+	}
+	return os.Rename(tmp, path)
+}
+
+type Importer struct {
+	DB        *sql.DB
+	Logger    *logrus.Logger
+	Creator   int
+	StatePath string
+	BatchSize int
+}
+
+// Run imports records of the reader, skipping ones processed by previous run with the same source
+func (imp *Importer) Run(reader Reader, source string) (*State, error) {
+	state, err := loadState(imp.StatePath, source)
+	if err != nil {
+		return nil, err
+	}
+	if state.Processed > 0 {
+		imp.Logger.Infof("Resuming after %d processed records", state.Processed)
+	}
+	batch := imp.BatchSize
+	if batch <= 0 {
+		batch = 500
+	}
+	position := 0
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return state, errors.Wrapf(err, "While reading record %d", position)
+		}
+		position++
+		if position <= state.Processed {
+			continue
+		}
+		imported, err := imp.importRecord(record)
+		if err != nil {
+			return state, errors.Wrapf(err, "While importing record %d", position)
+		}
+		if imported {
+			state.Imported++
+		} else {
+			state.Skipped++
+		}
+		state.Processed = position
+		if state.Processed%batch == 0 {
+			err = saveState(imp.StatePath, state)
+			if err != nil {
+				return state, err
+			}
+			imp.Logger.Infof("Processed %d, imported %d, skipped %d", state.Processed, state.Imported, state.Skipped)
+		}
+	}
+	err = saveState(imp.StatePath, state)
+	if err != nil {
+		return state, err
+	}
+	return state, nil
+}
+
+// importRecord creates product with single 100 g portion, returns false for invalid records and duplicates.
+// Duplicate checks and inserts run in one transaction, so a failed record leaves nothing behind to be skipped on resume.
+func (imp *Importer) importRecord(record *Record) (bool, error) {
+	name := NormalizeName(record.Name)
+	if name == "" || record.Nutrition.Energy <= 0 || record.Nutrition.Validate() != nil {
+		return false, nil
+	}
+	barcodes := []string{}
+	if record.Barcode != "" {
+		code, err := models.NormalizeBarcode(record.Barcode)
+		if err == nil {
+			barcodes = append(barcodes, code)
+		}
+	}
+	imported := false
+	err := models.WithTx(imp.DB, func(tx *sql.Tx) error {
+		for _, barcode := range barcodes {
+			exists, err := models.BarcodeExists(tx, barcode)
+			if err != nil {
+				return err
+			}
+			if exists {
+				return nil
+			}
+		}
+		exists, err := models.ProductNameExists(tx, name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return nil
+		}
+		portion := models.Portion{Unit: PortionUnit, Nutrition: record.Nutrition}
+		_, _, err = models.InsertProductWithPortions(tx, models.Product{
+			Creator:     imp.Creator,
+			Name:        name,
+			Description: record.Description,
+		}, []models.Portion{portion}, barcodes)
+		if err != nil {
+			return errors.Wrap(err, "While creating product")
+		}
+		imported = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return imported, nil
+}
@@ -0,0 +1,170 @@
+package importer
+
+import (
+	"app/service/models"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// offMicronutrients maps Open Food Facts nutriment names to micronutrient names, amounts are given in grams
+var offMicronutrients = map[string]string{
+	"vitamin-a":   "vitaminA",
+	"vitamin-c":   "vitaminC",
+	"vitamin-d":   "vitaminD",
+	"calcium":     "calcium",
+	"iron":        "iron",
+	"potassium":   "potassium",
+	"magnesium":   "magnesium",
+	"cholesterol": "cholesterol",
+}
+
+// offRecord builds record from Open Food Facts nutriments, lookup returns value of "<name>_100g" field
+func offRecord(code, name, description string, lookup func(string) (float64, bool)) *Record {
+	record := &Record{
+		Name:        name,
+		Description: description,
+		Barcode:     code,
+	}
+	if kcal, ok := lookup("energy-kcal"); ok {
+		record.Nutrition.Energy = kcal
+	} else if kj, ok := lookup("energy"); ok {
+		record.Nutrition.Energy = kj / kilojoulesPerKilocalorie
+	}
+	record.Nutrition.Protein, _ = lookup("proteins")
+	record.Nutrition.Carbohydrate, _ = lookup("carbohydrates")
+	record.Nutrition.Fat, _ = lookup("fat")
+	record.Nutrition.Fiber, _ = lookup("fiber")
+	record.Nutrition.Sugar, _ = lookup("sugars")
+	if sodium, ok := lookup("sodium"); ok {
+		record.Nutrition.Sodium = sodium * 1000
+	}
+	for offName, name := range offMicronutrients {
+		if amount, ok := lookup(offName); ok {
+			if record.Nutrition.Micronutrients == nil {
+				record.Nutrition.Micronutrients = models.Micronutrients{}
+			}
+			record.Nutrition.Micronutrients[name] = amount * 1000
+		}
+	}
+	return record
+}
+
+type offCSVReader struct {
+	reader *csv.Reader
+	header map[string]int
+}
+
+// NewOFFCSVReader reads Open Food Facts CSV export, the export is tab separated but comma separated files work too
+func NewOFFCSVReader(r io.Reader) (Reader, error) {
+	buffered := bufio.NewReader(r)
+	firstLine, err := buffered.Peek(4096)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, errors.Wrap(err, "While reading header")
+	}
+	reader := csv.NewReader(buffered)
+	reader.LazyQuotes = true
+	reader.FieldsPerRecord = -1
+	reader.ReuseRecord = true
+	if strings.Contains(strings.SplitN(string(firstLine), "\n", 2)[0], "\t") {
+		reader.Comma = '\t'
+	}
+	columns, err := reader.Read()
+	if err != nil {
+		return nil, errors.Wrap(err, "While reading header")
+	}
+	header := map[string]int{}
+	for i, column := range columns {
+		header[column] = i
+	}
+	if _, ok := header["product_name"]; !ok {
+		return nil, errors.New("Missing product_name column")
+	}
+	return &offCSVReader{reader: reader, header: header}, nil
+}
+
+func (r *offCSVReader) Next() (*Record, error) {
+	row, err := r.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	field := func(column string) string {
+		i, ok := r.header[column]
+		if !ok || i >= len(row) {
+			return ""
+		}
+		return strings.TrimSpace(row[i])
+	}
+	lookup := func(name string) (float64, bool) {
+		value := field(name + "_100g")
+		if value == "" {
+			return 0, false
+		}
+		parsed, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return 0, false
+		}
+		return parsed, true
+	}
+	return offRecord(field("code"), field("product_name"), field("generic_name"), lookup), nil
+}
+
+type offJSONLReader struct {
+	scanner *bufio.Scanner
+}
+
+// NewOFFJSONLReader reads Open Food Facts JSONL export, one product object per line
+func NewOFFJSONLReader(r io.Reader) (Reader, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 1024*1024), 64*1024*1024)
+	return &offJSONLReader{scanner: scanner}, nil
+}
+
+func (r *offJSONLReader) Next() (*Record, error) {
+	type Product struct {
+		Code        string                     `json:"code"`
+		ProductName string                     `json:"product_name"`
+		GenericName string                     `json:"generic_name"`
+		Nutriments  map[string]json.RawMessage `json:"nutriments"`
+	}
+	for r.scanner.Scan() {
+		line := strings.TrimSpace(r.scanner.Text())
+		if line == "" {
+			continue
+		}
+		product := Product{}
+		err := json.Unmarshal([]byte(line), &product)
+		if err != nil {
+			return nil, errors.Wrap(err, "While parsing line")
+		}
+		lookup := func(name string) (float64, bool) {
+			raw, ok := product.Nutriments[name+"_100g"]
+			if !ok {
+				return 0, false
+			}
+			var number float64
+			if json.Unmarshal(raw, &number) == nil {
+				return number, true
+			}
+			var text string
+			if json.Unmarshal(raw, &text) != nil {
+				return 0, false
+			}
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(text), 64)
+			if err != nil {
+				return 0, false
+			}
+			return parsed, true
+		}
+		return offRecord(product.Code, product.ProductName, product.GenericName, lookup), nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, errors.Wrap(err, "While reading line")
+	}
+	return nil, io.EOF
+}
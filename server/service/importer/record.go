@@ -0,0 +1,33 @@
+package importer
+
+import (
+	"app/service/models"
+	"regexp"
+	"strings"
+)
+
+// Record is a single food read from a dump, nutrition is given per 100 g.
+// Macronutrients are in grams, energy in kcal, sodium and micronutrients in milligrams.
+type Record struct {
+	Name        string
+	Description string
+	Barcode     string
+	Nutrition   models.Nutrition
+}
+
+// Reader reads records one by one from a dump, returns io.EOF when the dump ends
+type Reader interface {
+	Next() (*Record, error)
+}
+
+// PortionUnit is the unit of the single portion created for every imported product
+const PortionUnit = "100 g"
+
+const kilojoulesPerKilocalorie = 4.184
+
+var whitespace = regexp.MustCompile(`\s+`)
+
+// NormalizeName lowercases name and collapses whitespace, it is used to find duplicates
+func NormalizeName(name string) string {
+	return whitespace.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), " ")
+}
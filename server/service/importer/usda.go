@@ -0,0 +1,145 @@
+package importer
+
+import (
+	"app/service/models"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// usdaNutrients maps FoodData Central nutrient numbers to setters, amounts are given per 100 g
+var usdaNutrients = map[string]func(*models.Nutrition, float64){
+	"208":  func(n *models.Nutrition, v float64) { n.Energy = v },
+	"203":  func(n *models.Nutrition, v float64) { n.Protein = v },
+	"205":  func(n *models.Nutrition, v float64) { n.Carbohydrate = v },
+	"204":  func(n *models.Nutrition, v float64) { n.Fat = v },
+	"291":  func(n *models.Nutrition, v float64) { n.Fiber = v },
+	"269":  func(n *models.Nutrition, v float64) { n.Sugar = v },
+	"2000": func(n *models.Nutrition, v float64) { n.Sugar = v },
+	"307":  func(n *models.Nutrition, v float64) { n.Sodium = v },
+}
+
+// usdaMicronutrients maps FoodData Central nutrient numbers of nutrients given in milligrams to micronutrient names
+var usdaMicronutrients = map[string]string{
+	"401": "vitaminC",
+	"301": "calcium",
+	"303": "iron",
+	"306": "potassium",
+	"304": "magnesium",
+	"601": "cholesterol",
+}
+
+type usdaReader struct {
+	decoder *json.Decoder
+	inArray bool
+}
+
+// NewUSDAReader reads FoodData Central JSON download, foods of the first top level array are read one at a time
+func NewUSDAReader(r io.Reader) (Reader, error) {
+	decoder := json.NewDecoder(r)
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, errors.Wrap(err, "While reading dump")
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '{' {
+		return nil, errors.New("Dump has to be a JSON object")
+	}
+	return &usdaReader{decoder: decoder}, nil
+}
+
+func (r *usdaReader) openArray() error {
+	for {
+		token, err := r.decoder.Token()
+		if err != nil {
+			return err
+		}
+		if _, ok := token.(string); !ok {
+			return io.EOF
+		}
+		token, err = r.decoder.Token()
+		if err != nil {
+			return err
+		}
+		delim, ok := token.(json.Delim)
+		if !ok {
+			continue
+		}
+		if delim == '[' {
+			r.inArray = true
+			return nil
+		}
+		// skip nested object which is not a list of foods
+		for depth := 1; depth > 0; {
+			token, err = r.decoder.Token()
+			if err != nil {
+				return err
+			}
+			if delim, ok := token.(json.Delim); ok {
+				if delim == '{' || delim == '[' {
+					depth++
+				} else {
+					depth--
+				}
+			}
+		}
+	}
+}
+
+func (r *usdaReader) Next() (*Record, error) {
+	type Food struct {
+		Description   string `json:"description"`
+		GtinUpc       string `json:"gtinUpc"`
+		FoodNutrients []struct {
+			Amount   float64 `json:"amount"`
+			Nutrient struct {
+				Number   string `json:"number"`
+				UnitName string `json:"unitName"`
+			} `json:"nutrient"`
+		} `json:"foodNutrients"`
+	}
+	for {
+		if !r.inArray {
+			err := r.openArray()
+			if err != nil {
+				return nil, err
+			}
+		}
+		if !r.decoder.More() {
+			// closing bracket of the array
+			_, err := r.decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			r.inArray = false
+			continue
+		}
+		food := Food{}
+		err := r.decoder.Decode(&food)
+		if err != nil {
+			return nil, errors.Wrap(err, "While parsing food")
+		}
+		record := &Record{
+			Name:    food.Description,
+			Barcode: strings.TrimSpace(food.GtinUpc),
+		}
+		for _, nutrient := range food.FoodNutrients {
+			number := nutrient.Nutrient.Number
+			if set, ok := usdaNutrients[number]; ok {
+				if number == "208" && strings.ToLower(nutrient.Nutrient.UnitName) != "kcal" {
+					continue
+				}
+				set(&record.Nutrition, nutrient.Amount)
+				continue
+			}
+			if name, ok := usdaMicronutrients[number]; ok {
+				if record.Nutrition.Micronutrients == nil {
+					record.Nutrition.Micronutrients = models.Micronutrients{}
+				}
+				record.Nutrition.Micronutrients[name] = nutrient.Amount
+			}
+		}
+		return record, nil
+	}
+}
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"app/service/migrations"
 	"app/service/models"
 	"fmt"
 	"log"
@@ -13,7 +14,28 @@ import (
 	"github.com/pkg/errors"
 )
 
+// NewDBConnection opens the db, brings its schema up to date and seeds the accounts.
 func NewDBConnection() (*sql.DB, error) {
+	db, err := OpenDB()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := migrations.Up(db)
+	if err != nil {
+		return nil, errors.Wrap(err, "While migrating db")
+	}
+	for _, m := range applied {
+		log.Printf("Applied migration %s\n", m)
+	}
+	err = models.SeedAccounts(db, "/tmp/seeds/accounts.json")
+	if err != nil {
+		return nil, errors.Wrap(err, "While seeding accounts")
+	}
+	return db, nil
+}
+
+// OpenDB connects to the db configured by PG_* env variables without touching its schema.
+func OpenDB() (*sql.DB, error) {
 	host := os.Getenv("PG_HOST")
 	port := os.Getenv("PG_PORT")
 	user := os.Getenv("PG_USER")
@@ -30,25 +52,5 @@ func NewDBConnection() (*sql.DB, error) {
 	if err != nil {
 		return nil, errors.Wrap(err, "While opening connection to db")
 	}
-	err = models.MigrateAccounts(db)
-	if err != nil {
-		return nil, errors.Wrap(err, "While migrating accounts table")
-	}
-	err = models.MigrateProducts(db)
-	if err != nil {
-		return nil, errors.Wrap(err, "While migrating produts table")
-	}
-	err = models.MigratePortions(db)
-	if err != nil {
-		return nil, errors.Wrap(err, "While migrating portions table")
-	}
-	err = models.MigrateEntries(db)
-	if err != nil {
-		return nil, errors.Wrap(err, "While migrating entries table")
-	}
-	err = models.MigrateVotes(db)
-	if err != nil {
-		return nil, errors.Wrap(err, "While migrating votes")
-	}
 	return db, nil
 }
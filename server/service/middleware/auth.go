@@ -2,8 +2,7 @@ package middleware
 
 import (
 	"app/service/auth"
-	"app/service/models"
-	"database/sql"
+	"app/service/store"
 	"encoding/json"
 	"net/http"
 
@@ -28,7 +27,7 @@ func (res *ResponseObject) Send(w http.ResponseWriter) {
 	json.NewEncoder(w).Encode(*res)
 }
 
-func WithAuth(next http.Handler, db *sql.DB, accessLevel auth.AccessLevel) http.Handler {
+func WithAuth(next http.Handler, accounts store.AccountStore, accessLevel auth.AccessLevel) http.Handler {
 	const NotAuthenticated = "You are not authenticated, please login or register"
 	const Banished = "You accound have been banished"
 	const AccessDenied = "Access denied"
@@ -47,7 +46,7 @@ func WithAuth(next http.Handler, db *sql.DB, accessLevel auth.AccessLevel) http.
 			resObj.Send(w)
 			return
 		}
-		acc, err := models.GetAccountById(db, token.UserID)
+		acc, err := accounts.GetAccountById(token.UserID)
 		if err != nil {
 			resObj := ResponseObject{
 				Status: http.StatusForbidden,
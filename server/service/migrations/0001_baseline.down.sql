@@ -0,0 +1,6 @@
+-- The baseline holds accounts, products and diaries of every user, it is never dropped by a migration.
+DO $$
+BEGIN
+	RAISE EXCEPTION 'Baseline schema can not be reverted';
+END
+$$;
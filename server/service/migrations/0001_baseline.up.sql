@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS accounts (
+	id serial primary key,
+	email text unique,
+	password text,
+	access_level integer,
+	verified boolean default false,
+	change_password boolean default false
+);
+CREATE TABLE IF NOT EXISTS products (
+	id serial PRIMARY KEY,
+	creator integer REFERENCES accounts(id),
+	name text NOT NULL,
+	description text
+);
+CREATE TABLE IF NOT EXISTS portions (
+	id SERIAL PRIMARY KEY,
+	product_id INTEGER REFERENCES products(id),
+	unit text NOT NULL,
+	energy decimal NOT NULL
+);
+CREATE TABLE IF NOT EXISTS entries (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER REFERENCES accounts(id),
+	product_id INTEGER REFERENCES products(id),
+	portion_id INTEGER REFERENCES portions(id),
+	quantity DECIMAL,
+	date DATE NOT NULL DEFAULT CURRENT_DATE
+);
+CREATE TABLE IF NOT EXISTS votes (
+	user_id integer REFERENCES accounts(id),
+	product_id integer REFERENCES products(id),
+	vote integer NOT NULL,
+	PRIMARY KEY (user_id, product_id)
+);
@@ -0,0 +1,8 @@
+ALTER TABLE portions
+	DROP COLUMN IF EXISTS protein,
+	DROP COLUMN IF EXISTS carbohydrate,
+	DROP COLUMN IF EXISTS fat,
+	DROP COLUMN IF EXISTS fiber,
+	DROP COLUMN IF EXISTS sugar,
+	DROP COLUMN IF EXISTS sodium,
+	DROP COLUMN IF EXISTS micronutrients;
@@ -0,0 +1,8 @@
+ALTER TABLE portions
+	ADD COLUMN IF NOT EXISTS protein decimal NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS carbohydrate decimal NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS fat decimal NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS fiber decimal NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS sugar decimal NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS sodium decimal NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS micronutrients jsonb NOT NULL DEFAULT '{}';
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS goals (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES accounts(id),
+	energy DECIMAL NOT NULL,
+	weekday INTEGER CHECK (weekday BETWEEN 0 AND 6),
+	effective_from DATE NOT NULL DEFAULT CURRENT_DATE
+);
@@ -0,0 +1,2 @@
+ALTER TABLE entries DROP COLUMN IF EXISTS slot;
+DROP TABLE IF EXISTS meal_slots;
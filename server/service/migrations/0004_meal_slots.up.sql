@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS meal_slots (
+	user_id INTEGER REFERENCES accounts(id),
+	name TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	PRIMARY KEY (user_id, name)
+);
+ALTER TABLE entries ADD COLUMN IF NOT EXISTS slot TEXT NOT NULL DEFAULT '';
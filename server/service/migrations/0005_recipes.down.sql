@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS ingredients;
+ALTER TABLE products
+	DROP COLUMN IF EXISTS recipe,
+	DROP COLUMN IF EXISTS yield;
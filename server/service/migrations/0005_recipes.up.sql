@@ -0,0 +1,10 @@
+ALTER TABLE products
+	ADD COLUMN IF NOT EXISTS recipe boolean NOT NULL DEFAULT false,
+	ADD COLUMN IF NOT EXISTS yield decimal NOT NULL DEFAULT 0;
+CREATE TABLE IF NOT EXISTS ingredients (
+	id SERIAL PRIMARY KEY,
+	recipe_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	portion_id INTEGER NOT NULL REFERENCES portions(id) ON DELETE CASCADE,
+	quantity DECIMAL NOT NULL
+);
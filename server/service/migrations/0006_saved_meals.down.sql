@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS saved_meal_items;
+DROP TABLE IF EXISTS saved_meals;
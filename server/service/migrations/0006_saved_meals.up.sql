@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS saved_meals (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES accounts(id),
+	name TEXT NOT NULL,
+	UNIQUE (user_id, name)
+);
+CREATE TABLE IF NOT EXISTS saved_meal_items (
+	id SERIAL PRIMARY KEY,
+	meal_id INTEGER NOT NULL REFERENCES saved_meals(id) ON DELETE CASCADE,
+	product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	portion_id INTEGER NOT NULL REFERENCES portions(id) ON DELETE CASCADE,
+	quantity DECIMAL NOT NULL
+);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS weights (
+	id SERIAL PRIMARY KEY,
+	user_id INTEGER NOT NULL REFERENCES accounts(id),
+	date DATE NOT NULL DEFAULT CURRENT_DATE,
+	weight DECIMAL NOT NULL,
+	unit TEXT NOT NULL,
+	UNIQUE (user_id, date)
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS barcodes (
+	code TEXT PRIMARY KEY,
+	product_id INTEGER NOT NULL REFERENCES products(id)
+);
+CREATE INDEX IF NOT EXISTS barcodes_product_id_idx ON barcodes (product_id);
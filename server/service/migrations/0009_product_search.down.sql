@@ -0,0 +1,4 @@
+-- pg_trgm is kept, it may have been created by an operator and other database objects may use it.
+DROP INDEX IF EXISTS products_name_fts_idx;
+DROP INDEX IF EXISTS products_name_trgm_idx;
+DROP INDEX IF EXISTS products_name_idx;
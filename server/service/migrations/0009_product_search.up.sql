@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS products_name_idx ON products (name);
+-- Creating pg_trgm needs superuser or CREATE privilege on the database. When the service role has neither,
+-- an operator has to run CREATE EXTENSION pg_trgm; once before this migration, this line is then a no-op.
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+CREATE INDEX IF NOT EXISTS products_name_trgm_idx ON products USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS products_name_fts_idx ON products USING gin (to_tsvector('simple', name));
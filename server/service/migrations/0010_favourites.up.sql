@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS favourites (
+	user_id INTEGER NOT NULL REFERENCES accounts(id) ON DELETE CASCADE,
+	product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	created_at TIMESTAMP NOT NULL DEFAULT now(),
+	PRIMARY KEY (user_id, product_id)
+);
@@ -0,0 +1,5 @@
+-- Withdrawn votes removed by the up migration are not restored, they did not count as votes.
+DROP INDEX IF EXISTS votes_product_id_idx;
+ALTER TABLE votes
+	DROP COLUMN IF EXISTS created_at,
+	DROP COLUMN IF EXISTS updated_at;
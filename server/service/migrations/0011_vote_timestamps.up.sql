@@ -0,0 +1,6 @@
+ALTER TABLE votes
+	ADD COLUMN IF NOT EXISTS created_at TIMESTAMP NOT NULL DEFAULT now(),
+	ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP NOT NULL DEFAULT now();
+-- Votes were always stored as sent by the client, withdrawn votes stored as 0 are removed.
+DELETE FROM votes WHERE vote NOT IN (-1, 1);
+CREATE INDEX IF NOT EXISTS votes_product_id_idx ON votes (product_id);
@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS proposals (
+	id SERIAL PRIMARY KEY,
+	product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES accounts(id),
+	name TEXT,
+	description TEXT,
+	portions JSONB NOT NULL DEFAULT '[]',
+	comment TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'pending',
+	moderator_id INTEGER REFERENCES accounts(id),
+	review_note TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMP NOT NULL DEFAULT now(),
+	reviewed_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS proposals_status_idx ON proposals (status, created_at);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS product_revisions (
+	id SERIAL PRIMARY KEY,
+	product_id INTEGER NOT NULL REFERENCES products(id) ON DELETE CASCADE,
+	user_id INTEGER NOT NULL REFERENCES accounts(id),
+	action TEXT NOT NULL,
+	before JSONB NOT NULL,
+	after JSONB NOT NULL,
+	created_at TIMESTAMP NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS product_revisions_product_id_idx ON product_revisions (product_id, id);
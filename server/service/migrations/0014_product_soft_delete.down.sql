@@ -0,0 +1,4 @@
+ALTER TABLE products
+	DROP COLUMN IF EXISTS deleted_at,
+	DROP COLUMN IF EXISTS deleted_by,
+	DROP COLUMN IF EXISTS deleted;
@@ -0,0 +1,4 @@
+ALTER TABLE products
+	ADD COLUMN IF NOT EXISTS deleted boolean NOT NULL DEFAULT false,
+	ADD COLUMN IF NOT EXISTS deleted_by integer REFERENCES accounts(id),
+	ADD COLUMN IF NOT EXISTS deleted_at timestamp;
@@ -0,0 +1,3 @@
+ALTER TABLE portions
+	DROP COLUMN IF EXISTS amount,
+	DROP COLUMN IF EXISTS base_unit;
@@ -0,0 +1,11 @@
+ALTER TABLE portions
+	ADD COLUMN IF NOT EXISTS amount decimal NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS base_unit text NOT NULL DEFAULT '';
+-- Portions created before amount and base_unit existed only carry their size in the unit label.
+UPDATE portions SET
+	amount = parsed.parts[1]::decimal * CASE WHEN parsed.parts[2] IN ('kg', 'l') THEN 1000 ELSE 1 END,
+	base_unit = CASE WHEN parsed.parts[2] IN ('g', 'kg') THEN 'g' ELSE 'ml' END
+FROM (
+	SELECT id, regexp_match(lower(unit), '^\s*([0-9]+(?:\.[0-9]+)?)\s*(g|kg|ml|l)\s*$') AS parts FROM portions
+) AS parsed
+WHERE portions.id = parsed.id AND parsed.parts IS NOT NULL AND portions.base_unit = '';
@@ -0,0 +1,244 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// lockKey is the key of the postgres advisory lock held while migrating, so replicas starting
+// at the same time apply migrations one after another instead of racing each other.
+const lockKey = 4637721
+
+// Migration 0001 is the schema the service created before migrations existed, so databases from
+// that time start at it, and it refuses to be reverted. Every later change has its own numbered files
+// whose down file reverts only what the up file added. Applied migrations must not be edited,
+// new schema changes go into new numbered files.
+//
+//go:embed *.sql
+var files embed.FS
+
+var fileName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a numbered schema change with the SQL to apply and revert it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Status is a migration together with the time it was applied, nil if it is pending.
+type Status struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Load reads the embedded migrations sorted by version.
+func Load() ([]Migration, error) {
+	entries, err := files.ReadDir(".")
+	if err != nil {
+		return nil, errors.Wrap(err, "While reading migrations")
+	}
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := fileName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, errors.Errorf("Invalid migration file name %s", entry.Name())
+		}
+		version, _ := strconv.Atoi(match[1])
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+		if m.Name != match[2] {
+			return nil, errors.Errorf("Migration %d has files named %s and %s", version, m.Name, match[2])
+		}
+		body, err := files.ReadFile(entry.Name())
+		if err != nil {
+			return nil, errors.Wrapf(err, "While reading migration %s", entry.Name())
+		}
+		if match[3] == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+	migrations := []Migration{}
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, errors.Errorf("Migration %d %s needs both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// String formats migration as it is named on disk, e.g. 0003_portions.
+func (m Migration) String() string {
+	return fmt.Sprintf("%04d_%s", m.Version, m.Name)
+}
+
+// Up applies all pending migrations in order and returns the ones it applied.
+func Up(db *sql.DB) ([]Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	applied := []Migration{}
+	err = withLock(db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for _, m := range migrations {
+			if _, ok := done[m.Version]; ok {
+				continue
+			}
+			err = apply(conn, m.Up, `INSERT INTO schema_migrations (version, name) VALUES ($1, $2);`, m.Version, m.Name)
+			if err != nil {
+				return errors.Wrapf(err, "While applying migration %s", m)
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns the ones it reverted.
+func Down(db *sql.DB, steps int) ([]Migration, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	reverted := []Migration{}
+	err = withLock(db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			m := migrations[i]
+			if _, ok := done[m.Version]; !ok {
+				continue
+			}
+			err = apply(conn, m.Down, `DELETE FROM schema_migrations WHERE version = $1;`, m.Version)
+			if err != nil {
+				return errors.Wrapf(err, "While reverting migration %s", m)
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// GetStatus lists every known migration and when it was applied. It only reads schema_migrations,
+// so it neither waits for a running migration nor creates the table, all migrations are pending without it.
+func GetStatus(db *sql.DB) ([]Status, error) {
+	migrations, err := Load()
+	if err != nil {
+		return nil, err
+	}
+	var exists bool
+	err = db.QueryRow(`SELECT to_regclass('schema_migrations') IS NOT NULL;`).Scan(&exists)
+	if err != nil {
+		return nil, errors.Wrap(err, "While checking schema migrations table")
+	}
+	done := map[int]time.Time{}
+	if exists {
+		done, err = appliedVersions(db)
+		if err != nil {
+			return nil, err
+		}
+	}
+	statuses := []Status{}
+	for _, m := range migrations {
+		status := Status{Migration: m}
+		if appliedAt, ok := done[m.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// withLock runs fn on a single connection holding the migrations advisory lock. Session level
+// advisory locks belong to the connection, so everything must go through the same one.
+func withLock(db *sql.DB, fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return errors.Wrap(err, "While getting db connection")
+	}
+	defer conn.Close()
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, lockKey)
+	if err != nil {
+		return errors.Wrap(err, "While acquiring migrations lock")
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1);`, lockKey)
+	_, err = conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT now()
+		);
+	`)
+	if err != nil {
+		return errors.Wrap(err, "While creating schema migrations table")
+	}
+	return fn(conn)
+}
+
+// queryer is implemented by both *sql.DB and *sql.Conn
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+func appliedVersions(conn queryer) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(context.Background(), `SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, errors.Wrap(err, "While querying applied migrations")
+	}
+	defer rows.Close()
+	done := map[int]time.Time{}
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		err = rows.Scan(&version, &appliedAt)
+		if err != nil {
+			return nil, errors.Wrap(err, "While scanning applied migration")
+		}
+		done[version] = appliedAt
+	}
+	return done, rows.Err()
+}
+
+// apply runs migration body and the schema_migrations bookkeeping query in one transaction.
+func apply(conn *sql.Conn, body string, record string, args ...interface{}) error {
+	ctx := context.Background()
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	_, err = tx.ExecContext(ctx, body)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, record, args...)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
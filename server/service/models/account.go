@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
-	"math"
 	"strings"
 
 	"database/sql"
@@ -24,22 +23,9 @@ type Account struct {
 	ChangePassword bool             `json:"changePassword"`
 }
 
-func MigrateAccounts(db *sql.DB) error {
-	rows, err := db.Query(`
-		CREATE TABLE IF NOT EXISTS accounts (
-			id serial primary key, 
-			email text unique, 
-			password text, 
-			access_level integer,
-			verified boolean default false,
-			change_password boolean default false
-		);
-	`)
-	if err != nil {
-		return errors.Wrap(err, "While creating accounts table")
-	}
-	defer rows.Close()
-	f, err := ioutil.ReadFile("/tmp/seeds/accounts.json")
+// SeedAccounts upserts the accounts listed in the seed file at path, hashing their passwords.
+func SeedAccounts(db *sql.DB, path string) error {
+	f, err := ioutil.ReadFile(path)
 	if err != nil {
 		return errors.Wrap(err, "While loading seed file")
 	}
@@ -193,13 +179,23 @@ func SetAccessLevel(db *sql.DB, id int, accessLevel auth.AccessLevel) error {
 }
 
 func SearchAccounts(db *sql.DB, email string, pagination Pagination) (*[]Account, *Pagination, error) {
+	var lastEmail string
+	var lastID int
+	keyset, err := pagination.CursorKeys(&lastEmail, &lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	pattern := "%" + strings.ToLower(email) + "%"
+	limit := pagination.Limit()
 	rows, err := db.Query(`
-		SELECT * FROM accounts WHERE email LIKE $1 ORDER BY email, id ASC LIMIT $2 OFFSET $3;;
-	`, "%"+strings.ToLower(email)+"%", pagination.ItemsPerPage, pagination.ItemsPerPage*pagination.Page)
-	defer rows.Close()
+		SELECT * FROM accounts
+		WHERE email LIKE $1 AND (NOT $4 OR (email, id) > ($5, $6))
+		ORDER BY email, id ASC LIMIT $2 OFFSET $3;
+	`, pattern, limit+1, pagination.Offset(), keyset, lastEmail, lastID)
 	if err != nil {
 		return nil, nil, err
 	}
+	defer rows.Close()
 	accs := []Account{}
 	for rows.Next() {
 		acc := Account{}
@@ -210,18 +206,18 @@ func SearchAccounts(db *sql.DB, email string, pagination Pagination) (*[]Account
 		accs = append(accs, acc)
 	}
 	var count int
-	row := db.QueryRow("SELECT COUNT(*) FROM accounts")
+	row := db.QueryRow("SELECT COUNT(*) FROM accounts WHERE email LIKE $1", pattern)
 	err = row.Scan(&count)
 	if err != nil {
 		return nil, nil, err
 	}
-	maxPage := int(math.Ceil(float64(count)/float64(pagination.ItemsPerPage)) - 1)
-	newPagination := Pagination{
-		ItemsPerPage: pagination.ItemsPerPage,
-		Page:         pagination.Page,
-		MaxPage:      maxPage,
+	more := len(accs) > limit
+	if !more {
+		return &accs, pagination.Result(count, false), nil
 	}
-	return &accs, &newPagination, nil
+	accs = accs[:limit]
+	last := accs[limit-1]
+	return &accs, pagination.Result(count, true, last.Email, last.ID), nil
 }
 
 func ChangePasswordRequest(db *sql.DB, email string) error {
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// NormalizeBarcode validates EAN-8, EAN-13 or UPC-A code and returns it in stored form.
+// UPC-A codes are stored as EAN-13 with leading zero, so both scans of the same package match.
+func NormalizeBarcode(code string) (string, error) {
+	code = strings.TrimSpace(code)
+	for _, c := range code {
+		if c < '0' || c > '9' {
+			return "", errors.New("Barcode can contain only digits")
+		}
+	}
+	switch len(code) {
+	case 8, 13:
+	case 12:
+		code = "0" + code
+	default:
+		return "", errors.New("Barcode has to be EAN-8, EAN-13 or UPC-A")
+	}
+	if !validCheckDigit(code) {
+		return "", errors.New("Invalid barcode check digit")
+	}
+	return code, nil
+}
+
+// validCheckDigit verifies GTIN check digit, digits are weighted 3 and 1 alternately from the right
+func validCheckDigit(code string) bool {
+	sum := 0
+	body := code[:len(code)-1]
+	for i := range body {
+		digit := int(body[len(body)-1-i] - '0')
+		if i%2 == 0 {
+			digit *= 3
+		}
+		sum += digit
+	}
+	check := (10 - sum%10) % 10
+	return check == int(code[len(code)-1]-'0')
+}
+
+func CreateBarcode(db DBTX, code string, productID int) error {
+	rows, err := db.Query(`
+		INSERT INTO barcodes (code, product_id)
+		VALUES ($1, $2);
+	`, code, productID)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	return nil
+}
+
+func GetProductsBarcodes(db *sql.DB, productID int) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT code FROM barcodes WHERE product_id=$1 ORDER BY code;
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	codes := []string{}
+	for rows.Next() {
+		var code string
+		err := rows.Scan(&code)
+		if err != nil {
+			return nil, errors.Wrap(err, "While scaning row")
+		}
+		codes = append(codes, code)
+	}
+	return codes, nil
+}
+
+// BarcodeExists checks if barcode is assigned to any product, deleted ones included
+func BarcodeExists(db DBTX, code string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM barcodes WHERE code=$1);
+	`, code).Scan(&exists)
+	if err != nil {
+		return false, errors.Wrap(err, "While querying for barcode")
+	}
+	return exists, nil
+}
+
+// GetProductByBarcode returns not deleted product with given normalized barcode
+func GetProductByBarcode(db *sql.DB, code string) (*Product, error) {
+	var productID int
+	err := db.QueryRow(`
+		SELECT product_id FROM barcodes
+		JOIN products ON products.id = barcodes.product_id
+		WHERE code=$1 AND NOT products.deleted;
+	`, code).Scan(&productID)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "While querying for barcode")
+	}
+	return GetProductById(db, productID)
+}
@@ -0,0 +1,35 @@
+package models
+
+import "testing"
+
+func TestNormalizeBarcode(t *testing.T) {
+	cases := []struct {
+		name string
+		code string
+		want string
+		err  string
+	}{
+		{"EAN-13", "5901234123457", "5901234123457", ""},
+		{"EAN-8", "96385074", "96385074", ""},
+		{"UPC-A stored as EAN-13", "036000291452", "0036000291452", ""},
+		{"surrounding spaces", " 5901234123457 ", "5901234123457", ""},
+		{"wrong check digit", "5901234123458", "", "Invalid barcode check digit"},
+		{"wrong UPC-A check digit", "036000291453", "", "Invalid barcode check digit"},
+		{"letters", "59012341234a7", "", "Barcode can contain only digits"},
+		{"inner space", "590123 4123457", "", "Barcode can contain only digits"},
+		{"unsupported length", "1234567890", "", "Barcode has to be EAN-8, EAN-13 or UPC-A"},
+		{"empty", "", "", "Barcode has to be EAN-8, EAN-13 or UPC-A"},
+	}
+	for _, c := range cases {
+		code, err := NormalizeBarcode(c.code)
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("%s: expected error %q, got %q %v", c.name, c.err, code, err)
+			}
+			continue
+		}
+		if err != nil || code != c.want {
+			t.Errorf("%s: expected %q, got %q %v", c.name, c.want, code, err)
+		}
+	}
+}
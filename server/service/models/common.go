@@ -1,7 +1,8 @@
 package models
 
-type Pagination struct {
-	ItemsPerPage int `json:"itemsPerPage"`
-	Page         int `json:"page"`
-	MaxPage      int `json:"maxPage"`
+import "time"
+
+// Day truncates time to midnight UTC of the same calendar day, which is how DATE columns are scanned
+func Day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
 }
@@ -0,0 +1,300 @@
+package models
+
+import (
+	"database/sql"
+	"sort"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const RevisionMerge = "merge"
+
+// MinDuplicateSimilarity is the lowest name similarity duplicates are searched with, it matches default pg_trgm threshold
+const MinDuplicateSimilarity = 0.3
+
+// maxDuplicatePairs bounds number of candidate pairs clusters are built from
+const maxDuplicatePairs = 1000
+
+// maxNameCandidates bounds number of similarly named products looked up for each product,
+// so the scan costs one trigram index lookup per product instead of comparing every pair
+const maxNameCandidates = 5
+
+// DuplicatePair struct is used to represent two products which are likely the same food
+type DuplicatePair struct {
+	ProductID         int     `json:"productID"`
+	OtherID           int     `json:"otherID"`
+	Similarity        float64 `json:"similarity"`
+	IdenticalPortions bool    `json:"identicalPortions"`
+}
+
+// DuplicateCluster struct is used to represent group of products connected by duplicate pairs
+type DuplicateCluster struct {
+	ProductIDs []int           `json:"productIDs"`
+	Pairs      []DuplicatePair `json:"pairs"`
+}
+
+// duplicatePairs finds pairs of products whose names, with punctuation collapsed, are similar
+// or whose portions are identical. Each product is paired only with the $3 most similarly named ones.
+// Recipes are left out as their portions are derived, deleted products too.
+const duplicatePairs = `
+	WITH signatures AS (
+		SELECT portions.product_id, string_agg(
+			concat_ws('|', portions.unit, portions.energy::float8, portions.protein::float8, portions.carbohydrate::float8,
+				portions.fat::float8, portions.fiber::float8, portions.sugar::float8, portions.sodium::float8, portions.micronutrients::text),
+			';' ORDER BY portions.unit, portions.energy
+		) AS signature
+		FROM portions
+		JOIN products ON products.id = portions.product_id
+		WHERE NOT products.recipe AND NOT products.deleted
+		GROUP BY portions.product_id
+	), pairs AS (
+		SELECT a.id AS a_id, b.id AS b_id
+		FROM products a
+		CROSS JOIN LATERAL (
+			SELECT candidate.id FROM products candidate
+			WHERE candidate.name % a.name AND candidate.id > a.id AND NOT candidate.recipe AND NOT candidate.deleted
+			ORDER BY similarity(candidate.name, a.name) DESC, candidate.id
+			LIMIT $3
+		) AS b
+		WHERE NOT a.recipe AND NOT a.deleted
+		UNION
+		SELECT sa.product_id, sb.product_id
+		FROM signatures sa
+		JOIN signatures sb ON sa.product_id < sb.product_id AND sa.signature = sb.signature
+	), scored AS (
+		SELECT pairs.a_id, pairs.b_id,
+			similarity(
+				btrim(regexp_replace(a.name, '[^[:alnum:]]+', ' ', 'g')),
+				btrim(regexp_replace(b.name, '[^[:alnum:]]+', ' ', 'g'))
+			) AS similarity,
+			COALESCE(sa.signature = sb.signature, false) AS identical
+		FROM pairs
+		JOIN products a ON a.id = pairs.a_id
+		JOIN products b ON b.id = pairs.b_id
+		LEFT JOIN signatures sa ON sa.product_id = pairs.a_id
+		LEFT JOIN signatures sb ON sb.product_id = pairs.b_id
+	)
+	SELECT a_id, b_id, similarity, identical FROM scored
+	WHERE similarity >= $1 OR identical
+	ORDER BY identical DESC, similarity DESC, a_id, b_id
+	LIMIT $2;
+`
+
+// FindDuplicateClusters groups candidate duplicate pairs into clusters, the clusters with most products first
+func FindDuplicateClusters(db *sql.DB, minSimilarity float64, limit int) ([]DuplicateCluster, error) {
+	rows, err := db.Query(duplicatePairs, minSimilarity, maxDuplicatePairs, maxNameCandidates)
+	if err != nil {
+		return nil, errors.Wrap(err, "While querying for duplicates")
+	}
+	defer rows.Close()
+	pairs := []DuplicatePair{}
+	for rows.Next() {
+		pair := DuplicatePair{}
+		err := rows.Scan(&pair.ProductID, &pair.OtherID, &pair.Similarity, &pair.IdenticalPortions)
+		if err != nil {
+			return nil, errors.Wrap(err, "While scaning row")
+		}
+		pairs = append(pairs, pair)
+	}
+	parent := map[int]int{}
+	var find func(id int) int
+	find = func(id int) int {
+		if _, ok := parent[id]; !ok {
+			parent[id] = id
+		}
+		if parent[id] != id {
+			parent[id] = find(parent[id])
+		}
+		return parent[id]
+	}
+	for _, pair := range pairs {
+		a, b := find(pair.ProductID), find(pair.OtherID)
+		if a != b {
+			parent[b] = a
+		}
+	}
+	clusters := map[int]*DuplicateCluster{}
+	for _, pair := range pairs {
+		root := find(pair.ProductID)
+		cluster, ok := clusters[root]
+		if !ok {
+			cluster = &DuplicateCluster{ProductIDs: []int{}, Pairs: []DuplicatePair{}}
+			clusters[root] = cluster
+		}
+		cluster.Pairs = append(cluster.Pairs, pair)
+	}
+	for id := range parent {
+		cluster := clusters[find(id)]
+		cluster.ProductIDs = append(cluster.ProductIDs, id)
+	}
+	out := []DuplicateCluster{}
+	for _, cluster := range clusters {
+		sort.Ints(cluster.ProductIDs)
+		out = append(out, *cluster)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if len(out[i].ProductIDs) != len(out[j].ProductIDs) {
+			return len(out[i].ProductIDs) > len(out[j].ProductIDs)
+		}
+		return out[i].ProductIDs[0] < out[j].ProductIDs[0]
+	})
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// MergeProducts moves everything referencing duplicates to the surviving product and deletes the duplicates,
+// all in one transaction together with recomputing recipes using the survivor. Portions identical to a portion
+// of the survivor are folded into it, votes and favourites of users who already have one on the survivor are dropped.
+// Survivor can't be a recipe or a deleted product. Duplicates are soft deleted and keep their own history,
+// a merge revision is recorded for the survivor and for every duplicate.
+func MergeProducts(db *sql.DB, survivorID int, duplicateIDs []int, userID int) (*Revision, error) {
+	ids := pq.Array(duplicateIDs)
+	var revision *Revision
+	err := WithTx(db, func(tx *sql.Tx) error {
+		before, err := snapshotProduct(tx, survivorID)
+		if err != nil {
+			return err
+		}
+		var survivorRecipe, survivorDeleted bool
+		err = tx.QueryRow(`
+			SELECT recipe, deleted FROM products WHERE id=$1;
+		`, survivorID).Scan(&survivorRecipe, &survivorDeleted)
+		if err != nil {
+			return errors.Wrap(err, "While querying for survivor")
+		}
+		if survivorRecipe {
+			return errors.New("Products can't be merged into a recipe")
+		}
+		if survivorDeleted {
+			return errors.New("Products can't be merged into a deleted product")
+		}
+		var found int
+		var recipes bool
+		err = tx.QueryRow(`
+			SELECT COUNT(*), COALESCE(bool_or(recipe), false) FROM (
+				SELECT recipe FROM products WHERE id = ANY($1) FOR UPDATE
+			) AS duplicates;
+		`, ids).Scan(&found, &recipes)
+		if err != nil {
+			return errors.Wrap(err, "While locking duplicates")
+		}
+		if found != len(duplicateIDs) {
+			return ErrNotFound
+		}
+		if recipes {
+			return errors.New("Recipes can't be merged")
+		}
+		duplicatesBefore := map[int]*ProductSnapshot{}
+		for _, id := range duplicateIDs {
+			duplicatesBefore[id], err = snapshotProduct(tx, id)
+			if err != nil {
+				return err
+			}
+		}
+		var usedBySurvivor bool
+		err = tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM ingredients WHERE recipe_id=$1 AND product_id = ANY($2));
+		`, survivorID, ids).Scan(&usedBySurvivor)
+		if err != nil {
+			return errors.Wrap(err, "While checking ingredients")
+		}
+		if usedBySurvivor {
+			return errors.New("Recipe can't be merged with its own ingredient")
+		}
+		statements := []struct {
+			query   string
+			context string
+		}{
+			{`UPDATE entries SET product_id=$1 WHERE product_id = ANY($2);`, "While moving entries"},
+			{`UPDATE portions SET product_id=$1 WHERE product_id = ANY($2);`, "While moving portions"},
+			{`UPDATE barcodes SET product_id=$1 WHERE product_id = ANY($2);`, "While moving barcodes"},
+			{`UPDATE ingredients SET product_id=$1 WHERE product_id = ANY($2);`, "While moving ingredients"},
+			{`UPDATE saved_meal_items SET product_id=$1 WHERE product_id = ANY($2);`, "While moving saved meal items"},
+			{`
+				INSERT INTO votes (user_id, product_id, vote, created_at, updated_at)
+				SELECT DISTINCT ON (user_id) user_id, $1, vote, created_at, updated_at FROM votes
+				WHERE product_id = ANY($2)
+				ORDER BY user_id, updated_at DESC
+				ON CONFLICT (user_id, product_id) DO NOTHING;
+			`, "While moving votes"},
+			{`DELETE FROM votes WHERE product_id = ANY($2);`, "While moving votes"},
+			{`
+				INSERT INTO favourites (user_id, product_id, created_at)
+				SELECT user_id, $1, MIN(created_at) FROM favourites
+				WHERE product_id = ANY($2)
+				GROUP BY user_id
+				ON CONFLICT (user_id, product_id) DO NOTHING;
+			`, "While moving favourites"},
+			{`DELETE FROM favourites WHERE product_id = ANY($2);`, "While moving favourites"},
+			{`UPDATE proposals SET product_id=$1 WHERE product_id = ANY($2);`, "While moving proposals"},
+		}
+		for _, statement := range statements {
+			_, err = tx.Exec(statement.query, survivorID, ids)
+			if err != nil {
+				return errors.Wrap(err, statement.context)
+			}
+		}
+		_, err = tx.Exec(`
+			UPDATE products SET deleted=true, deleted_by=$2, deleted_at=now() WHERE id = ANY($1) AND NOT deleted;
+		`, ids, userID)
+		if err != nil {
+			return errors.Wrap(err, "While deleting duplicates")
+		}
+		err = foldIdenticalPortions(tx, survivorID)
+		if err != nil {
+			return err
+		}
+		revision, err = recordRevision(tx, survivorID, userID, RevisionMerge, before)
+		if err != nil {
+			return err
+		}
+		for _, id := range duplicateIDs {
+			_, err = recordRevision(tx, id, userID, RevisionMerge, duplicatesBefore[id])
+			if err != nil {
+				return err
+			}
+		}
+		return RefreshRecipesUsingProduct(tx, survivorID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// foldIdenticalPortions re-points references of portions identical to an older portion of the product and removes them
+func foldIdenticalPortions(tx *sql.Tx, productID int) error {
+	_, err := tx.Exec(`
+		CREATE TEMPORARY TABLE folded_portions ON COMMIT DROP AS
+		SELECT duplicate.id AS duplicate_id, MIN(kept.id) AS kept_id
+		FROM portions duplicate
+		JOIN portions kept ON kept.product_id = duplicate.product_id AND kept.id < duplicate.id
+			AND kept.unit = duplicate.unit AND kept.energy = duplicate.energy AND kept.protein = duplicate.protein
+			AND kept.carbohydrate = duplicate.carbohydrate AND kept.fat = duplicate.fat AND kept.fiber = duplicate.fiber
+			AND kept.sugar = duplicate.sugar AND kept.sodium = duplicate.sodium AND kept.micronutrients = duplicate.micronutrients
+		WHERE duplicate.product_id=$1
+		GROUP BY duplicate.id;
+	`, productID)
+	if err != nil {
+		return errors.Wrap(err, "While finding identical portions")
+	}
+	for _, table := range []string{"entries", "ingredients", "saved_meal_items"} {
+		_, err = tx.Exec(`
+			UPDATE ` + table + ` SET portion_id = folded_portions.kept_id
+			FROM folded_portions WHERE ` + table + `.portion_id = folded_portions.duplicate_id;
+		`)
+		if err != nil {
+			return errors.Wrap(err, "While re-pointing identical portions")
+		}
+	}
+	_, err = tx.Exec(`
+		DELETE FROM portions USING folded_portions WHERE portions.id = folded_portions.duplicate_id;
+	`)
+	if err != nil {
+		return errors.Wrap(err, "While removing identical portions")
+	}
+	return nil
+}
@@ -16,6 +16,77 @@ type Entry struct {
 	PortionID int       `json:"portionID"`
 	Quantity  float64   `json:"quantity"`
 	Date      time.Time `json:"date"`
+	Slot      string    `json:"slot"`
+}
+
+const entryColumns = `id, user_id, product_id, portion_id, quantity, date, slot`
+
+// Limits of values entry can be logged with
+const (
+	MaxEntryQuantity  = 10000
+	MaxEntryDaysAhead = 366
+)
+
+// MinEntryDate is the earliest date entry can be logged on
+var MinEntryDate = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// EntryPatch holds fields of entry to change, nil fields are left as they are
+type EntryPatch struct {
+	ProductID *int       `json:"productID,omitempty"`
+	PortionID *int       `json:"portionID,omitempty"`
+	Quantity  *float64   `json:"quantity,omitempty"`
+	Date      *time.Time `json:"date,omitempty"`
+	Slot      *string    `json:"slot,omitempty"`
+}
+
+// Apply returns copy of the entry with patched fields replaced
+func (patch EntryPatch) Apply(entry Entry) Entry {
+	if patch.ProductID != nil {
+		entry.ProductID = *patch.ProductID
+	}
+	if patch.PortionID != nil {
+		entry.PortionID = *patch.PortionID
+	}
+	if patch.Quantity != nil {
+		entry.Quantity = *patch.Quantity
+	}
+	if patch.Date != nil {
+		entry.Date = *patch.Date
+	}
+	if patch.Slot != nil {
+		entry.Slot = NormalizeSlot(*patch.Slot)
+	}
+	return entry
+}
+
+// ValidateEntry checks that entry refers to the given product and one of its portions, has positive quantity,
+// a date between MinEntryDate and MaxEntryDaysAhead days after today and one of given slots.
+// Product and portion are nil when they don't exist. Invalid fields are returned as *ValidationError.
+func ValidateEntry(entry Entry, product *Product, portion *Portion, slots []string, today time.Time) error {
+	invalid := &ValidationError{}
+	if math.IsNaN(entry.Quantity) || math.IsInf(entry.Quantity, 0) || entry.Quantity <= 0 {
+		invalid.Add("quantity", "Quantity has to be a positive number")
+	} else if entry.Quantity > MaxEntryQuantity {
+		invalid.Add("quantity", "Quantity is too large")
+	}
+	date := Day(entry.Date)
+	if entry.Date.IsZero() || date.Before(MinEntryDate) {
+		invalid.Add("date", "Date is missing or too far in the past")
+	} else if date.After(Day(today).AddDate(0, 0, MaxEntryDaysAhead)) {
+		invalid.Add("date", "Date is too far in the future")
+	}
+	if !HasSlot(slots, entry.Slot) {
+		invalid.Add("slot", "Unknown meal slot")
+	}
+	switch {
+	case product == nil || product.ID != entry.ProductID:
+		invalid.Add("productID", "Product does not exist")
+	case product.Deleted:
+		invalid.Add("productID", "Product was deleted")
+	case portion == nil || portion.ID != entry.PortionID || portion.ProductID != entry.ProductID:
+		invalid.Add("portionID", "Portion does not belong to the product")
+	}
+	return invalid.Err()
 }
 
 func (entry *Entry) scanRow(rows *sql.Rows) error {
@@ -26,6 +97,7 @@ func (entry *Entry) scanRow(rows *sql.Rows) error {
 		&entry.PortionID,
 		&entry.Quantity,
 		&entry.Date,
+		&entry.Slot,
 	)
 	if err != nil {
 		return errors.Wrap(err, "While scaning row")
@@ -33,30 +105,12 @@ func (entry *Entry) scanRow(rows *sql.Rows) error {
 	return err
 }
 
-func MigrateEntries(db *sql.DB) error {
-	rows, err := db.Query(`
-		CREATE TABLE IF NOT EXISTS entries (
-			id SERIAL PRIMARY KEY, 
-			user_id INTEGER REFERENCES accounts(id),
-			product_id INTEGER REFERENCES products(id),
-			portion_id INTEGER REFERENCES portions(id),  
-			quantity DECIMAL,
-			date DATE NOT NULL DEFAULT CURRENT_DATE
-		);
-	`)
-	if err != nil {
-		return err
-	}
-	defer rows.Close()
-	return nil
-}
-
 func CreateEntry(db *sql.DB, entry *Entry) (*Entry, error) {
 	rows, err := db.Query(`
-		INSERT INTO entries (user_id, product_id, portion_id, quantity, date)
-		VALUES ($1, $2, $3, $4, $5)
-		RETURNING *;
-	`, entry.UserID, entry.ProductID, entry.PortionID, entry.Quantity, entry.Date)
+		INSERT INTO entries (user_id, product_id, portion_id, quantity, date, slot)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+entryColumns+`;
+	`, entry.UserID, entry.ProductID, entry.PortionID, entry.Quantity, entry.Date, entry.Slot)
 	if err != nil {
 		return nil, err
 	}
@@ -78,7 +132,7 @@ func CreateEntry(db *sql.DB, entry *Entry) (*Entry, error) {
 
 func GetEntry(db *sql.DB, id int) (*Entry, error) {
 	rows, err := db.Query(`
-		SELECT * FROM entries WHERE id = $1;
+		SELECT `+entryColumns+` FROM entries WHERE id = $1;
 	`, id)
 	if err != nil {
 		return nil, err
@@ -94,7 +148,7 @@ func GetEntry(db *sql.DB, id int) (*Entry, error) {
 		entries = append(entries, entry)
 	}
 	if len(entries) == 0 {
-		return nil, errors.New("Not found")
+		return nil, ErrNotFound
 	}
 	if len(entries) > 1 {
 		return nil, errors.New("Two entries with the same id")
@@ -102,10 +156,31 @@ func GetEntry(db *sql.DB, id int) (*Entry, error) {
 	return entries[0], err
 }
 
-func GetUsersEntries(db *sql.DB, userID int, date time.Time, pagination *Pagination) (*[]Entry, *Pagination, error) {
-	rows, err := db.Query(`
-		SELECT * FROM entries WHERE user_id=$1 AND date=$2;
-	`, userID, date)
+// GetUsersEntries returns entries of the days between from and to (inclusive) ordered by date,
+// all of them when pagination is nil
+func GetUsersEntries(db *sql.DB, userID int, from, to time.Time, pagination *Pagination) (*[]Entry, *Pagination, error) {
+	page := Pagination{}
+	if pagination != nil {
+		page = *pagination
+	}
+	var lastDate time.Time
+	var lastID int
+	keyset, err := page.CursorKeys(&lastDate, &lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	limit := page.Limit()
+	query := `
+		SELECT ` + entryColumns + ` FROM entries
+		WHERE user_id=$1 AND date BETWEEN $2 AND $3 AND (NOT $4 OR (date, id) > ($5, $6))
+		ORDER BY date, id
+	`
+	args := []interface{}{userID, from, to, keyset, lastDate, lastID}
+	if pagination != nil {
+		query += " LIMIT $7 OFFSET $8"
+		args = append(args, limit+1, page.Offset())
+	}
+	rows, err := db.Query(query, args...)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -119,19 +194,22 @@ func GetUsersEntries(db *sql.DB, userID int, date time.Time, pagination *Paginat
 		}
 		entries = append(entries, *entry)
 	}
+	if pagination == nil {
+		return &entries, nil, nil
+	}
 	var count int
-	row := db.QueryRow("SELECT COUNT(*) FROM entries;")
+	row := db.QueryRow("SELECT COUNT(*) FROM entries WHERE user_id=$1 AND date BETWEEN $2 AND $3", userID, from, to)
 	err = row.Scan(&count)
 	if err != nil {
 		return nil, nil, err
 	}
-	maxPage := int(math.Ceil(float64(count)/float64(pagination.ItemsPerPage)) - 1)
-	newPagination := Pagination{
-		ItemsPerPage: pagination.ItemsPerPage,
-		Page:         pagination.Page,
-		MaxPage:      maxPage,
+	more := len(entries) > limit
+	if !more {
+		return &entries, page.Result(count, false), nil
 	}
-	return &entries, &newPagination, nil
+	entries = entries[:limit]
+	last := entries[limit-1]
+	return &entries, page.Result(count, true, last.Date, last.ID), nil
 }
 
 func GetUsersEntryDates(db *sql.DB, userID int) (*[]time.Time, error) {
@@ -168,11 +246,97 @@ func DeleteEntry(db *sql.DB, id int) error {
 
 func UpdateEntry(db *sql.DB, id int, new *Entry) error {
 	rows, err := db.Query(`
-		UPDATE entries SET product_id=$2, quantity=$3, date=$4 WHERE id=$1;
-	`, id, new.ProductID, new.Quantity, new.Date)
+		UPDATE entries SET product_id=$2, portion_id=$3, quantity=$4, date=$5, slot=$6 WHERE id=$1;
+	`, id, new.ProductID, new.PortionID, new.Quantity, new.Date, new.Slot)
 	if err != nil {
 		return errors.Wrap(err, "While updating entry")
 	}
 	defer rows.Close()
 	return nil
 }
+
+// CopyEntries creates copy of every entry on each of given dates, either all of them are created or none.
+// Copies which are not valid entries, e.g. of a deleted product, fail with *ValidationError.
+// Dry run checks the copies the same way and returns them without creating anything.
+func CopyEntries(db *sql.DB, entries []Entry, dates []time.Time, dryRun bool) ([]Entry, error) {
+	copies := []Entry{}
+	today := time.Now()
+	err := WithTx(db, func(tx *sql.Tx) error {
+		for _, date := range dates {
+			for _, entry := range entries {
+				entry.ID = 0
+				entry.Date = date
+				if dryRun {
+					err := checkEntry(tx, entry, today)
+					if err != nil {
+						return err
+					}
+					copies = append(copies, entry)
+					continue
+				}
+				dbEntry, err := insertValidEntry(tx, entry, today)
+				if err != nil {
+					return err
+				}
+				copies = append(copies, *dbEntry)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return copies, nil
+}
+
+// checkEntry checks entry with ValidateEntry against its product, portion and users slots read with db
+func checkEntry(db DBTX, entry Entry, today time.Time) error {
+	slots, err := GetUsersSlots(db, entry.UserID)
+	if err != nil {
+		return err
+	}
+	product, err := GetProductById(db, entry.ProductID)
+	if errors.Cause(err) == ErrNotFound {
+		product = nil
+	} else if err != nil {
+		return err
+	}
+	portion, err := GetPortion(db, entry.PortionID)
+	if errors.Cause(err) == ErrNotFound {
+		portion = nil
+	} else if err != nil {
+		return err
+	}
+	return ValidateEntry(entry, product, portion, slots, today)
+}
+
+// insertValidEntry checks entry with checkEntry in the given transaction and inserts it,
+// so entries logged in bulk follow the same rules as a single created entry
+func insertValidEntry(tx *sql.Tx, entry Entry, today time.Time) (*Entry, error) {
+	err := checkEntry(tx, entry, today)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := tx.Query(`
+		INSERT INTO entries (user_id, product_id, portion_id, quantity, date, slot)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+entryColumns+`;
+	`, entry.UserID, entry.ProductID, entry.PortionID, entry.Quantity, entry.Date, entry.Slot)
+	if err != nil {
+		return nil, errors.Wrap(err, "While inserting entry")
+	}
+	defer rows.Close()
+	entries := []Entry{}
+	for rows.Next() {
+		entry := Entry{}
+		err := entry.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 1 {
+		return nil, errors.New("Invalid return of insert operation")
+	}
+	return &entries[0], nil
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// AddFavourite marks product as users favourite, adding it again is not an error.
+// Missing and deleted products can not be favourites, ErrNotFound is returned for them.
+func AddFavourite(db *sql.DB, userID, productID int) error {
+	return WithTx(db, func(tx *sql.Tx) error {
+		var deleted bool
+		err := tx.QueryRow(`
+			SELECT deleted FROM products WHERE id=$1 FOR SHARE;
+		`, productID).Scan(&deleted)
+		if err == sql.ErrNoRows || (err == nil && deleted) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return errors.Wrap(err, "While querying for product")
+		}
+		_, err = tx.Exec(`
+			INSERT INTO favourites (user_id, product_id)
+			VALUES ($1, $2)
+			ON CONFLICT (user_id, product_id) DO NOTHING;
+		`, userID, productID)
+		if err != nil {
+			return errors.Wrap(err, "While adding favourite")
+		}
+		return nil
+	})
+}
+
+func RemoveFavourite(db *sql.DB, userID, productID int) error {
+	rows, err := db.Query(`
+		DELETE FROM favourites WHERE user_id=$1 AND product_id=$2;
+	`, userID, productID)
+	if err != nil {
+		return errors.Wrap(err, "While removing favourite")
+	}
+	defer rows.Close()
+	return nil
+}
+
+func IsFavourite(db *sql.DB, userID, productID int) (bool, error) {
+	var favourite bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM favourites WHERE user_id=$1 AND product_id=$2);
+	`, userID, productID).Scan(&favourite)
+	if err != nil {
+		return false, errors.Wrap(err, "While querying for favourite")
+	}
+	return favourite, nil
+}
+
+// GetUsersFavourites returns users favourite products which are not deleted, the most recently added first
+func GetUsersFavourites(db *sql.DB, userID int, pagination Pagination) (*[]Product, *Pagination, error) {
+	var lastAdded time.Time
+	var lastID int
+	keyset, err := pagination.CursorKeys(&lastAdded, &lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	limit := pagination.Limit()
+	rows, err := db.Query(`
+		SELECT `+qualifiedProductColumns+`, favourites.created_at FROM favourites
+		JOIN products ON products.id = favourites.product_id
+		WHERE favourites.user_id=$1 AND NOT products.deleted
+			AND (NOT $4 OR (favourites.created_at, products.id) < ($5, $6))
+		ORDER BY favourites.created_at DESC, products.id DESC LIMIT $2 OFFSET $3;
+	`, userID, limit+1, pagination.Offset(), keyset, lastAdded, lastID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "While querying for favourites")
+	}
+	defer rows.Close()
+	prods := []Product{}
+	added := []time.Time{}
+	for rows.Next() {
+		prod := Product{}
+		var createdAt time.Time
+		err := prod.scanRow(rows, &createdAt)
+		if err != nil {
+			return nil, nil, err
+		}
+		prods = append(prods, prod)
+		added = append(added, createdAt)
+	}
+	var count int
+	row := db.QueryRow(`
+		SELECT COUNT(*) FROM favourites
+		JOIN products ON products.id = favourites.product_id
+		WHERE favourites.user_id=$1 AND NOT products.deleted
+	`, userID)
+	err = row.Scan(&count)
+	if err != nil {
+		return nil, nil, err
+	}
+	more := len(prods) > limit
+	if !more {
+		return &prods, pagination.Result(count, false), nil
+	}
+	prods = prods[:limit]
+	return &prods, pagination.Result(count, true, added[limit-1], prods[limit-1].ID), nil
+}
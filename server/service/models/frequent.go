@@ -0,0 +1,84 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// FrequentHalfLife is the number of days after which weight of a logged entry halves
+const FrequentHalfLife = 14
+
+// FrequentWindow is the number of days of entries taken into account
+const FrequentWindow = 180
+
+// LoggedProduct struct is used to represent product user logs, with the portion and quantity logged most often
+type LoggedProduct struct {
+	ProductID  int       `json:"productID"`
+	PortionID  int       `json:"portionID"`
+	Quantity   float64   `json:"quantity"`
+	Uses       int       `json:"uses"`
+	LastLogged time.Time `json:"lastLogged"`
+	Score      float64   `json:"score"`
+}
+
+// loggedProducts weights every entry by 0.5^(age / half life), so recent entries count more than old ones.
+// Usual portion and quantity is the pair with the highest weight, latest entry wins ties.
+const loggedProducts = `
+	WITH weighted AS (
+		SELECT entries.id, entries.product_id, entries.portion_id, entries.quantity, entries.date,
+			power(0.5, ($2::date - entries.date) / $3::float) AS weight
+		FROM entries
+		JOIN portions ON portions.id = entries.portion_id
+		JOIN products ON products.id = entries.product_id
+		WHERE NOT products.deleted AND entries.user_id=$1 AND entries.date <= $2::date AND entries.date > $2::date - $4::int
+	), usual AS (
+		SELECT DISTINCT ON (product_id) product_id, portion_id, quantity
+		FROM weighted
+		GROUP BY product_id, portion_id, quantity
+		ORDER BY product_id, SUM(weight) DESC, MAX(id) DESC
+	)
+	SELECT weighted.product_id, usual.portion_id, usual.quantity,
+		COUNT(*) AS uses, MAX(weighted.date) AS last_logged, SUM(weighted.weight) AS score
+	FROM weighted
+	JOIN usual ON usual.product_id = weighted.product_id
+	GROUP BY weighted.product_id, usual.portion_id, usual.quantity
+`
+
+func getUsersLoggedProducts(db *sql.DB, userID int, today time.Time, orderBy string, limit int) ([]LoggedProduct, error) {
+	rows, err := db.Query(loggedProducts+`
+		ORDER BY `+orderBy+`, weighted.product_id DESC LIMIT $5;
+	`, userID, today, FrequentHalfLife, FrequentWindow, limit)
+	if err != nil {
+		return nil, errors.Wrap(err, "While querying logged products")
+	}
+	defer rows.Close()
+	products := []LoggedProduct{}
+	for rows.Next() {
+		product := LoggedProduct{}
+		err := rows.Scan(
+			&product.ProductID,
+			&product.PortionID,
+			&product.Quantity,
+			&product.Uses,
+			&product.LastLogged,
+			&product.Score,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "While scaning row")
+		}
+		products = append(products, product)
+	}
+	return products, nil
+}
+
+// GetUsersRecentProducts returns products logged lately, the most recent first
+func GetUsersRecentProducts(db *sql.DB, userID int, today time.Time, limit int) ([]LoggedProduct, error) {
+	return getUsersLoggedProducts(db, userID, today, "last_logged DESC, MAX(weighted.id) DESC", limit)
+}
+
+// GetUsersFrequentProducts returns products logged often, ranked by sum of time decayed entry weights
+func GetUsersFrequentProducts(db *sql.DB, userID int, today time.Time, limit int) ([]LoggedProduct, error) {
+	return getUsersLoggedProducts(db, userID, today, "score DESC", limit)
+}
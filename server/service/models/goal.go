@@ -0,0 +1,201 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Goal struct is used to represent users daily energy target.
+// Goal with nil Weekday applies to every day, otherwise it overrides the target on given weekday (0 - Sunday).
+type Goal struct {
+	ID            int       `json:"id"`
+	UserID        int       `json:"userID"`
+	Energy        float64   `json:"energy"`
+	Weekday       *int      `json:"weekday,omitempty"`
+	EffectiveFrom time.Time `json:"effectiveFrom"`
+}
+
+const goalColumns = `id, user_id, energy, weekday, effective_from`
+
+func (goal *Goal) scanRow(rows *sql.Rows) error {
+	var weekday sql.NullInt64
+	err := rows.Scan(
+		&goal.ID,
+		&goal.UserID,
+		&goal.Energy,
+		&weekday,
+		&goal.EffectiveFrom,
+	)
+	if err != nil {
+		return errors.Wrap(err, "While scaning row")
+	}
+	goal.Weekday = nil
+	if weekday.Valid {
+		day := int(weekday.Int64)
+		goal.Weekday = &day
+	}
+	return nil
+}
+
+// SetGoal creates goal, replacing users goal with the same weekday and effective date
+func SetGoal(db *sql.DB, goal Goal) (*Goal, error) {
+	var weekday interface{}
+	if goal.Weekday != nil {
+		weekday = *goal.Weekday
+	}
+	var created *Goal
+	err := WithTx(db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			DELETE FROM goals WHERE user_id=$1 AND weekday IS NOT DISTINCT FROM $2 AND effective_from=$3;
+		`, goal.UserID, weekday, goal.EffectiveFrom)
+		if err != nil {
+			return errors.Wrap(err, "While replacing goal")
+		}
+		rows, err := tx.Query(`
+			INSERT INTO goals (user_id, energy, weekday, effective_from)
+			VALUES ($1, $2, $3, $4)
+			RETURNING `+goalColumns+`;
+		`, goal.UserID, goal.Energy, weekday, goal.EffectiveFrom)
+		if err != nil {
+			return errors.Wrap(err, "While inserting goal")
+		}
+		goals := []Goal{}
+		for rows.Next() {
+			goal := Goal{}
+			err := goal.scanRow(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			goals = append(goals, goal)
+		}
+		rows.Close()
+		if len(goals) != 1 {
+			return errors.New("Invalid return of insert operation")
+		}
+		created = &goals[0]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+func GetGoal(db *sql.DB, id int) (*Goal, error) {
+	rows, err := db.Query(`
+		SELECT `+goalColumns+` FROM goals WHERE id=$1;
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	goals := []Goal{}
+	for rows.Next() {
+		goal := Goal{}
+		err := goal.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, goal)
+	}
+	if len(goals) == 0 {
+		return nil, ErrNotFound
+	}
+	return &goals[0], nil
+}
+
+func GetUsersGoals(db *sql.DB, userID int) ([]Goal, error) {
+	rows, err := db.Query(`
+		SELECT `+goalColumns+` FROM goals WHERE user_id=$1 ORDER BY effective_from, weekday NULLS FIRST;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	goals := []Goal{}
+	for rows.Next() {
+		goal := Goal{}
+		err := goal.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		goals = append(goals, goal)
+	}
+	return goals, nil
+}
+
+func DeleteGoal(db *sql.DB, id int) error {
+	rows, err := db.Query(`
+		DELETE FROM goals WHERE id=$1;
+	`, id)
+	if err != nil {
+		return errors.Wrap(err, "While deleting goal")
+	}
+	defer rows.Close()
+	return nil
+}
+
+// GoalForDate picks goal which applies to given date.
+// Latest effective override for the date's weekday wins, latest effective default goal is used only when there is none,
+// so setting a new default goal does not cancel earlier weekday overrides.
+func GoalForDate(goals []Goal, date time.Time) *Goal {
+	day := Day(date)
+	var override, fallback *Goal
+	for i := range goals {
+		goal := &goals[i]
+		if Day(goal.EffectiveFrom).After(day) {
+			continue
+		}
+		if goal.Weekday == nil {
+			if fallback == nil || Day(goal.EffectiveFrom).After(Day(fallback.EffectiveFrom)) {
+				fallback = goal
+			}
+			continue
+		}
+		if time.Weekday(*goal.Weekday) != day.Weekday() {
+			continue
+		}
+		if override == nil || Day(goal.EffectiveFrom).After(Day(override.EffectiveFrom)) {
+			override = goal
+		}
+	}
+	if override != nil {
+		return override
+	}
+	return fallback
+}
+
+type BudgetStatus string
+
+const (
+	NoGoal BudgetStatus = "none"
+	Under  BudgetStatus = "under"
+	Over   BudgetStatus = "over"
+)
+
+// Budget struct is used to represent how much energy is left from daily goal
+type Budget struct {
+	Goal      float64      `json:"goal"`
+	Consumed  float64      `json:"consumed"`
+	Remaining float64      `json:"remaining"`
+	Status    BudgetStatus `json:"status"`
+}
+
+func NewBudget(goal *Goal, consumed float64) Budget {
+	if goal == nil {
+		return Budget{Consumed: consumed, Status: NoGoal}
+	}
+	budget := Budget{
+		Goal:      goal.Energy,
+		Consumed:  consumed,
+		Remaining: goal.Energy - consumed,
+		Status:    Under,
+	}
+	if budget.Remaining < 0 {
+		budget.Status = Over
+	}
+	return budget
+}
@@ -0,0 +1,48 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGoalForDate(t *testing.T) {
+	date := func(day int) time.Time {
+		return time.Date(2024, time.January, day, 0, 0, 0, 0, time.UTC)
+	}
+	weekday := func(day time.Weekday) *int {
+		w := int(day)
+		return &w
+	}
+	// January 1st 2024 is a Monday
+	goals := []Goal{
+		{ID: 1, Energy: 2000, EffectiveFrom: date(1)},
+		{ID: 2, Energy: 2500, Weekday: weekday(time.Saturday), EffectiveFrom: date(1)},
+		{ID: 3, Energy: 1800, EffectiveFrom: date(15)},
+		{ID: 4, Energy: 2700, Weekday: weekday(time.Saturday), EffectiveFrom: date(20)},
+	}
+	cases := []struct {
+		name  string
+		goals []Goal
+		date  time.Time
+		want  int
+	}{
+		{"no goals", nil, date(10), 0},
+		{"before any goal", goals, date(1).AddDate(0, 0, -1), 0},
+		{"default goal", goals, date(2), 1},
+		{"weekday override", goals, date(6), 2},
+		{"later default goal", goals, date(16), 3},
+		{"override kept after new default goal", goals, date(20), 4},
+		{"earlier override after new default goal", goals, date(13), 2},
+		{"time of day is ignored", goals, date(15).Add(23 * time.Hour), 3},
+	}
+	for _, c := range cases {
+		goal := GoalForDate(c.goals, c.date)
+		got := 0
+		if goal != nil {
+			got = goal.ID
+		}
+		if got != c.want {
+			t.Errorf("%s: expected goal %d, got %d", c.name, c.want, got)
+		}
+	}
+}
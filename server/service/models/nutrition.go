@@ -0,0 +1,116 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+// Micronutrients maps nutrient name (e.g. "vitaminC", "iron") to its amount
+type Micronutrients map[string]float64
+
+// Value stores micronutrients as jsonb
+func (m Micronutrients) Value() (driver.Value, error) {
+	if m == nil {
+		return []byte("{}"), nil
+	}
+	return json.Marshal(m)
+}
+
+// Scan reads micronutrients from jsonb column
+func (m *Micronutrients) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*m = Micronutrients{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("Invalid micronutrients type")
+	}
+	out := Micronutrients{}
+	err := json.Unmarshal(data, &out)
+	if err != nil {
+		return errors.Wrap(err, "While parsing micronutrients")
+	}
+	*m = out
+	return nil
+}
+
+// Nutrition struct holds energy and nutrient amounts of a portion or a sum of portions
+type Nutrition struct {
+	Energy         float64        `json:"energy"`
+	Protein        float64        `json:"protein"`
+	Carbohydrate   float64        `json:"carbohydrate"`
+	Fat            float64        `json:"fat"`
+	Fiber          float64        `json:"fiber"`
+	Sugar          float64        `json:"sugar"`
+	Sodium         float64        `json:"sodium"`
+	Micronutrients Micronutrients `json:"micronutrients,omitempty"`
+}
+
+// Scale returns nutrition multiplied by quantity
+func (n Nutrition) Scale(quantity float64) Nutrition {
+	out := Nutrition{
+		Energy:       n.Energy * quantity,
+		Protein:      n.Protein * quantity,
+		Carbohydrate: n.Carbohydrate * quantity,
+		Fat:          n.Fat * quantity,
+		Fiber:        n.Fiber * quantity,
+		Sugar:        n.Sugar * quantity,
+		Sodium:       n.Sodium * quantity,
+	}
+	if len(n.Micronutrients) > 0 {
+		out.Micronutrients = Micronutrients{}
+		for name, amount := range n.Micronutrients {
+			out.Micronutrients[name] = amount * quantity
+		}
+	}
+	return out
+}
+
+// Add returns sum of both nutritions
+func (n Nutrition) Add(other Nutrition) Nutrition {
+	out := Nutrition{
+		Energy:       n.Energy + other.Energy,
+		Protein:      n.Protein + other.Protein,
+		Carbohydrate: n.Carbohydrate + other.Carbohydrate,
+		Fat:          n.Fat + other.Fat,
+		Fiber:        n.Fiber + other.Fiber,
+		Sugar:        n.Sugar + other.Sugar,
+		Sodium:       n.Sodium + other.Sodium,
+	}
+	if len(n.Micronutrients) > 0 || len(other.Micronutrients) > 0 {
+		out.Micronutrients = Micronutrients{}
+		for name, amount := range n.Micronutrients {
+			out.Micronutrients[name] += amount
+		}
+		for name, amount := range other.Micronutrients {
+			out.Micronutrients[name] += amount
+		}
+	}
+	return out
+}
+
+// Validate checks if nutrient amounts are not negative
+func (n Nutrition) Validate() error {
+	values := []float64{n.Energy, n.Protein, n.Carbohydrate, n.Fat, n.Fiber, n.Sugar, n.Sodium}
+	for _, value := range values {
+		if value < 0 {
+			return errors.New("Nutrient values cannot be negative")
+		}
+	}
+	for name, amount := range n.Micronutrients {
+		if name == "" {
+			return errors.New("Micronutrient name cannot be empty")
+		}
+		if amount < 0 {
+			return errors.New("Nutrient values cannot be negative")
+		}
+	}
+	return nil
+}
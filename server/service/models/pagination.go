@@ -0,0 +1,95 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+
+	"github.com/pkg/errors"
+)
+
+const DefaultItemsPerPage = 20
+const MaxItemsPerPage = 100
+
+// Pagination is sent with list requests and returned with their results.
+// Page is selected by its number, or by Cursor when it is set. NextCursor of a result points right after
+// its last item, so following it does not skip or repeat items when rows are added in the meantime.
+type Pagination struct {
+	ItemsPerPage int    `json:"itemsPerPage"`
+	Page         int    `json:"page"`
+	MaxPage      int    `json:"maxPage"`
+	TotalItems   int    `json:"totalItems"`
+	Cursor       string `json:"cursor,omitempty"`
+	NextCursor   string `json:"nextCursor,omitempty"`
+}
+
+// Limit returns number of items on a page
+func (p Pagination) Limit() int {
+	if p.ItemsPerPage <= 0 {
+		return DefaultItemsPerPage
+	}
+	if p.ItemsPerPage > MaxItemsPerPage {
+		return MaxItemsPerPage
+	}
+	return p.ItemsPerPage
+}
+
+// Offset returns number of items before the page, it is zero for cursor pages as the cursor already skips them
+func (p Pagination) Offset() int {
+	if p.Cursor != "" || p.Page < 0 {
+		return 0
+	}
+	return p.Page * p.Limit()
+}
+
+// CursorKeys decodes cursor into pointers to sort key values of the last item of the previous page,
+// returns false when the page is selected by its number
+func (p Pagination) CursorKeys(keys ...interface{}) (bool, error) {
+	if p.Cursor == "" {
+		return false, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err != nil {
+		return false, errors.Wrap(err, "Invalid cursor")
+	}
+	values := []json.RawMessage{}
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		return false, errors.Wrap(err, "Invalid cursor")
+	}
+	if len(values) != len(keys) {
+		return false, errors.New("Invalid cursor")
+	}
+	for i, value := range values {
+		err = json.Unmarshal(value, keys[i])
+		if err != nil {
+			return false, errors.Wrap(err, "Invalid cursor")
+		}
+	}
+	return true, nil
+}
+
+func encodeCursor(keys ...interface{}) string {
+	data, _ := json.Marshal(keys)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// Result builds pagination returned with the page, count is the number of all items matching the query
+// and more tells if items follow the page, in which case next cursor is built from the given sort keys
+func (p Pagination) Result(count int, more bool, last ...interface{}) *Pagination {
+	limit := p.Limit()
+	maxPage := (count+limit-1)/limit - 1
+	if maxPage < 0 {
+		maxPage = 0
+	}
+	newPagination := Pagination{
+		ItemsPerPage: limit,
+		Page:         p.Page,
+		MaxPage:      maxPage,
+		TotalItems:   count,
+		Cursor:       p.Cursor,
+	}
+	if more {
+		newPagination.NextCursor = encodeCursor(last...)
+	}
+	return &newPagination
+}
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPaginationResult(t *testing.T) {
+	cases := []struct {
+		name       string
+		pagination Pagination
+		count      int
+		more       bool
+		want       Pagination
+	}{
+		{"no items", Pagination{}, 0, false, Pagination{ItemsPerPage: DefaultItemsPerPage, MaxPage: 0}},
+		{"default page size", Pagination{}, 45, true, Pagination{ItemsPerPage: DefaultItemsPerPage, MaxPage: 2, TotalItems: 45}},
+		{"full last page", Pagination{ItemsPerPage: 10, Page: 1}, 20, false, Pagination{ItemsPerPage: 10, Page: 1, MaxPage: 1, TotalItems: 20}},
+		{"page size capped", Pagination{ItemsPerPage: 1000}, 250, true, Pagination{ItemsPerPage: MaxItemsPerPage, MaxPage: 2, TotalItems: 250}},
+		{"cursor kept", Pagination{ItemsPerPage: 10, Cursor: "abc"}, 11, false, Pagination{ItemsPerPage: 10, MaxPage: 1, TotalItems: 11, Cursor: "abc"}},
+	}
+	for _, c := range cases {
+		got := c.pagination.Result(c.count, c.more, "name", 7)
+		if c.more != (got.NextCursor != "") {
+			t.Errorf("%s: expected next cursor only when more items follow, got %q", c.name, got.NextCursor)
+		}
+		got.NextCursor = ""
+		if *got != c.want {
+			t.Errorf("%s: expected %+v, got %+v", c.name, c.want, *got)
+		}
+	}
+}
+
+func TestPaginationCursorKeys(t *testing.T) {
+	date := time.Date(2024, time.May, 4, 0, 0, 0, 0, time.UTC)
+	next := Pagination{}.Result(100, true, date, "apple", 42).NextCursor
+
+	var lastDate time.Time
+	var lastName string
+	var lastID int
+	keyset, err := Pagination{Cursor: next}.CursorKeys(&lastDate, &lastName, &lastID)
+	if err != nil || !keyset {
+		t.Fatalf("Expected cursor to be decoded, got %v %v", keyset, err)
+	}
+	if !lastDate.Equal(date) || lastName != "apple" || lastID != 42 {
+		t.Errorf("Expected keys of the last item, got %v %q %d", lastDate, lastName, lastID)
+	}
+	if offset := (Pagination{Cursor: next, Page: 3}).Offset(); offset != 0 {
+		t.Errorf("Expected cursor page not to be offset, got %d", offset)
+	}
+
+	cases := []struct {
+		name   string
+		cursor string
+		keyset bool
+		valid  bool
+	}{
+		{"page selected by number", "", false, true},
+		{"not base64", "!!!", false, false},
+		{"not json", "bm90IGpzb24", false, false},
+		{"too few keys", encodeCursor("apple", 42), false, false},
+		{"wrong key type", encodeCursor(date, 42, "apple"), false, false},
+	}
+	for _, c := range cases {
+		keyset, err := Pagination{Cursor: c.cursor}.CursorKeys(&lastDate, &lastName, &lastID)
+		if keyset != c.keyset || (err == nil) != c.valid {
+			t.Errorf("%s: expected keyset %v and valid %v, got %v %v", c.name, c.keyset, c.valid, keyset, err)
+		}
+	}
+}
@@ -2,39 +2,83 @@ package models
 
 import (
 	"database/sql"
+	"strings"
 
 	"github.com/pkg/errors"
 )
 
+// Portion struct is used to represent unit product is measured in together with its nutrition.
+// Amount is the size of the portion in BaseUnit (grams or millilitres), it is zero when the size is unknown.
 type Portion struct {
 	ID        int     `json:"id"`
 	ProductID int     `json:"productID"`
 	Unit      string  `json:"unit"`
-	Energy    float64 `json:"energy"`
+	Amount    float64 `json:"amount,omitempty"`
+	BaseUnit  string  `json:"baseUnit,omitempty"`
+	Nutrition
 }
 
-func MigratePortions(db *sql.DB) error {
-	rows, err := db.Query(`
-		CREATE TABLE IF NOT EXISTS portions (
-			id SERIAL PRIMARY KEY, 
-			product_id INTEGER REFERENCES products(id),  
-			unit text NOT NULL,
-			energy decimal NOT NULL
-		);
-	`)
+const portionColumns = `id, product_id, unit, amount, base_unit, energy, protein, carbohydrate, fat, fiber, sugar, sodium, micronutrients`
+
+// Validate checks unit, base amount and nutrition of the portion
+func (portion Portion) Validate() error {
+	if strings.TrimSpace(portion.Unit) == "" {
+		return errors.New("Portion unit cannot be empty")
+	}
+	if !ValidBaseUnit(portion.BaseUnit) {
+		return errors.New("Unknown base unit")
+	}
+	if portion.Amount < 0 || (portion.BaseUnit != "" && portion.Amount == 0) {
+		return errors.New("Portion amount has to be positive")
+	}
+	if portion.BaseUnit == "" && portion.Amount != 0 {
+		return errors.New("Portion amount needs a base unit")
+	}
+	return portion.Nutrition.Validate()
+}
+
+// WithBase fills base amount parsed from the unit, when neither base amount nor base unit is given explicitly
+func (portion Portion) WithBase() Portion {
+	if portion.BaseUnit != "" || portion.Amount != 0 {
+		return portion
+	}
+	if amount, base, ok := ParseUnit(portion.Unit); ok {
+		portion.Amount = amount
+		portion.BaseUnit = base
+	}
+	return portion
+}
+
+func (portion *Portion) scanRow(rows *sql.Rows) error {
+	err := rows.Scan(
+		&portion.ID,
+		&portion.ProductID,
+		&portion.Unit,
+		&portion.Amount,
+		&portion.BaseUnit,
+		&portion.Energy,
+		&portion.Protein,
+		&portion.Carbohydrate,
+		&portion.Fat,
+		&portion.Fiber,
+		&portion.Sugar,
+		&portion.Sodium,
+		&portion.Micronutrients,
+	)
 	if err != nil {
-		return err
+		return errors.Wrap(err, "While scaning row")
 	}
-	defer rows.Close()
-	return err
+	return nil
 }
 
-func CreatePortion(db *sql.DB, portion Portion) (*Portion, error) {
+func CreatePortion(db DBTX, portion Portion) (*Portion, error) {
+	portion = portion.WithBase()
 	rows, err := db.Query(`
-		INSERT INTO portions (product_id, unit, energy)
-		VALUES ($1, $2, $3)
-		RETURNING *;
-	`, portion.ProductID, portion.Unit, portion.Energy)
+		INSERT INTO portions (product_id, unit, amount, base_unit, energy, protein, carbohydrate, fat, fiber, sugar, sodium, micronutrients)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING `+portionColumns+`;
+	`, portion.ProductID, portion.Unit, portion.Amount, portion.BaseUnit, portion.Energy, portion.Protein, portion.Carbohydrate,
+		portion.Fat, portion.Fiber, portion.Sugar, portion.Sodium, portion.Micronutrients)
 	if err != nil {
 		return nil, errors.Wrap(err, "Invalid insert operation")
 	}
@@ -42,7 +86,7 @@ func CreatePortion(db *sql.DB, portion Portion) (*Portion, error) {
 	portions := []Portion{}
 	for rows.Next() {
 		portion := Portion{}
-		rows.Scan(&portion.ID, &portion.ProductID, &portion.Unit, &portion.Energy)
+		err := portion.scanRow(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -54,9 +98,9 @@ func CreatePortion(db *sql.DB, portion Portion) (*Portion, error) {
 	return &portions[0], err
 }
 
-func GetPortion(db *sql.DB, id int) (*Portion, error) {
+func GetPortion(db DBTX, id int) (*Portion, error) {
 	rows, err := db.Query(`
-		SELECT * FROM portions WHERE id = $1 
+		SELECT `+portionColumns+` FROM portions WHERE id = $1
 	`, id)
 	if err != nil {
 		return nil, err
@@ -65,18 +109,24 @@ func GetPortion(db *sql.DB, id int) (*Portion, error) {
 	portions := []*Portion{}
 	for rows.Next() {
 		portion := &Portion{}
-		rows.Scan(&portion.ID, &portion.ProductID, &portion.Unit, &portion.Energy)
+		err := portion.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
 		portions = append(portions, portion)
 	}
+	if len(portions) == 0 {
+		return nil, ErrNotFound
+	}
 	if len(portions) > 1 {
 		return nil, errors.New("Two portions with the same id")
 	}
 	return portions[0], err
 }
 
-func GetProductsPortions(db *sql.DB, productID int) ([]Portion, error) {
+func GetProductsPortions(db DBTX, productID int) ([]Portion, error) {
 	rows, err := db.Query(`
-		SELECT * FROM portions WHERE product_id=$1 
+		SELECT `+portionColumns+` FROM portions WHERE product_id=$1 ORDER BY id
 	`, productID)
 	if err != nil {
 		return nil, err
@@ -85,7 +135,7 @@ func GetProductsPortions(db *sql.DB, productID int) ([]Portion, error) {
 	portions := []Portion{}
 	for rows.Next() {
 		portion := Portion{}
-		err := rows.Scan(&portion.ID, &portion.ProductID, &portion.Unit, &portion.Energy)
+		err := portion.scanRow(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -94,9 +144,38 @@ func GetProductsPortions(db *sql.DB, productID int) ([]Portion, error) {
 	return portions, err
 }
 
-func DeletePortion(db *sql.DB, id int) error {
+// UpdatePortion updates portion of the product given by portion.ProductID, "Not found" is returned for portions of other products
+func UpdatePortion(db DBTX, portion Portion) (*Portion, error) {
+	portion = portion.WithBase()
 	rows, err := db.Query(`
-		DELETE FROM portions WHERE id=$1 
+		UPDATE portions SET unit=$3, amount=$4, base_unit=$5, energy=$6, protein=$7, carbohydrate=$8, fat=$9, fiber=$10,
+			sugar=$11, sodium=$12, micronutrients=$13
+		WHERE id=$1 AND product_id=$2
+		RETURNING `+portionColumns+`;
+	`, portion.ID, portion.ProductID, portion.Unit, portion.Amount, portion.BaseUnit, portion.Energy, portion.Protein,
+		portion.Carbohydrate, portion.Fat, portion.Fiber, portion.Sugar, portion.Sodium, portion.Micronutrients)
+	if err != nil {
+		return nil, errors.Wrap(err, "While updating portion")
+	}
+	defer rows.Close()
+	portions := []Portion{}
+	for rows.Next() {
+		portion := Portion{}
+		err := portion.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		portions = append(portions, portion)
+	}
+	if len(portions) == 0 {
+		return nil, ErrNotFound
+	}
+	return &portions[0], nil
+}
+
+func DeletePortion(db DBTX, id int) error {
+	rows, err := db.Query(`
+		DELETE FROM portions WHERE id=$1
 	`, id)
 	if err != nil {
 		return errors.Wrap(err, "While deleting portion")
@@ -104,3 +183,90 @@ func DeletePortion(db *sql.DB, id int) error {
 	defer rows.Close()
 	return nil
 }
+
+// AddProductPortion adds portion to an existing product and records the change as a revision made by given user
+func AddProductPortion(db *sql.DB, portion Portion, userID int) (*Portion, error) {
+	var created *Portion
+	err := WithTx(db, func(tx *sql.Tx) error {
+		before, err := snapshotProduct(tx, portion.ProductID)
+		if err != nil {
+			return err
+		}
+		created, err = CreatePortion(tx, portion)
+		if err != nil {
+			return err
+		}
+		_, err = recordRevision(tx, portion.ProductID, userID, RevisionPortions, before)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// EditProductPortion changes unit, base amount and nutrition of the portion, records the change as a revision
+// and recomputes recipes using the product
+func EditProductPortion(db *sql.DB, portion Portion, userID int) (*Portion, error) {
+	var updated *Portion
+	err := WithTx(db, func(tx *sql.Tx) error {
+		before, err := snapshotProduct(tx, portion.ProductID)
+		if err != nil {
+			return err
+		}
+		updated, err = UpdatePortion(tx, portion)
+		if err != nil {
+			return err
+		}
+		_, err = recordRevision(tx, portion.ProductID, userID, RevisionPortions, before)
+		if err != nil {
+			return err
+		}
+		return RefreshRecipesUsingProduct(tx, portion.ProductID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// RemoveProductPortion deletes portion of the product and records the change as a revision.
+// Portions which are logged or used by a recipe or saved meal, and the last portion of a product, are kept.
+func RemoveProductPortion(db *sql.DB, productID, portionID, userID int) error {
+	return WithTx(db, func(tx *sql.Tx) error {
+		before, err := snapshotProduct(tx, productID)
+		if err != nil {
+			return err
+		}
+		if len(before.Portions) < 2 {
+			return errors.New("Product has to keep at least one portion")
+		}
+		var used bool
+		err = tx.QueryRow(`
+			SELECT EXISTS (SELECT 1 FROM entries WHERE portion_id=$1)
+				OR EXISTS (SELECT 1 FROM ingredients WHERE portion_id=$1)
+				OR EXISTS (SELECT 1 FROM saved_meal_items WHERE portion_id=$1);
+		`, portionID).Scan(&used)
+		if err != nil {
+			return errors.Wrap(err, "While checking portion usage")
+		}
+		if used {
+			return errors.New("Portion is in use")
+		}
+		result, err := tx.Exec(`
+			DELETE FROM portions WHERE id=$1 AND product_id=$2;
+		`, portionID, productID)
+		if err != nil {
+			return errors.Wrap(err, "While deleting portion")
+		}
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if affected != 1 {
+			return ErrNotFound
+		}
+		_, err = recordRevision(tx, productID, userID, RevisionPortions, before)
+		return err
+	})
+}
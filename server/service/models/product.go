@@ -2,41 +2,63 @@ package models
 
 import (
 	"database/sql"
-	"math"
 	"strings"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
 type Product struct {
-	ID          int    `json:"id"`
-	Name        string `json:"name"`
-	Creator     int    `json:"creator"`
-	Description string `json:"description"`
+	ID          int        `json:"id"`
+	Name        string     `json:"name"`
+	Creator     int        `json:"creator"`
+	Description string     `json:"description"`
+	Recipe      bool       `json:"recipe"`
+	Yield       float64    `json:"yield,omitempty"`
+	Deleted     bool       `json:"deleted,omitempty"`
+	DeletedBy   int        `json:"deletedBy,omitempty"`
+	DeletedAt   *time.Time `json:"deletedAt,omitempty"`
 }
 
-func MigrateProducts(db *sql.DB) error {
-	rows, err := db.Query(`
-		CREATE TABLE IF NOT EXISTS products (
-			id serial PRIMARY KEY,
-			creator integer REFERENCES accounts(id),
-			name text NOT NULL,
-			description text
-		);
-	`)
+const productColumns = `id, creator, name, description, recipe, yield, deleted, deleted_by, deleted_at`
+
+const qualifiedProductColumns = `products.id, products.creator, products.name, products.description, products.recipe, products.yield,
+	products.deleted, products.deleted_by, products.deleted_at`
+
+func (prod *Product) scanRow(rows *sql.Rows, extra ...interface{}) error {
+	var description sql.NullString
+	var deletedBy sql.NullInt64
+	var deletedAt sql.NullTime
+	dest := []interface{}{
+		&prod.ID,
+		&prod.Creator,
+		&prod.Name,
+		&description,
+		&prod.Recipe,
+		&prod.Yield,
+		&prod.Deleted,
+		&deletedBy,
+		&deletedAt,
+	}
+	err := rows.Scan(append(dest, extra...)...)
 	if err != nil {
-		return errors.Wrap(err, "While creating products table")
+		return errors.Wrap(err, "While scaning row")
+	}
+	prod.Description = description.String
+	prod.DeletedBy = int(deletedBy.Int64)
+	prod.DeletedAt = nil
+	if deletedAt.Valid {
+		prod.DeletedAt = &deletedAt.Time
 	}
-	defer rows.Close()
 	return nil
 }
 
-func CreateProduct(db *sql.DB, product Product) (*Product, error) {
+func CreateProduct(db DBTX, product Product) (*Product, error) {
 	rows, err := db.Query(`
-		INSERT INTO products (creator, name, description)
-		VALUES ($1, $2, $3)
-		RETURNING *;
-	`, product.Creator, strings.ToLower(product.Name), product.Description)
+		INSERT INTO products (creator, name, description, recipe, yield)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+productColumns+`;
+	`, product.Creator, normalizeProductName(product.Name), product.Description, product.Recipe, product.Yield)
 	if err != nil {
 		return nil, err
 	}
@@ -44,7 +66,7 @@ func CreateProduct(db *sql.DB, product Product) (*Product, error) {
 	prods := []Product{}
 	for rows.Next() {
 		prod := Product{}
-		err := rows.Scan(&prod.ID, &prod.Creator, &prod.Name, &prod.Description)
+		err := prod.scanRow(rows)
 		if err != nil {
 			return nil, err
 		}
@@ -53,9 +75,95 @@ func CreateProduct(db *sql.DB, product Product) (*Product, error) {
 	return &prods[0], nil
 }
 
-func GetProductById(db *sql.DB, id int) (*Product, error) {
+// ErrProductExists is returned when a product with the same name already exists
+var ErrProductExists = errors.New("Product with same name already exists")
+
+// ErrEmptyProductName is returned when a product is given a blank name
+var ErrEmptyProductName = errors.New("Product name cannot be empty")
+
+// ErrProductDeleted is returned when a deleted product is changed, it has to be restored first
+var ErrProductDeleted = errors.New("Product was deleted")
+
+// normalizeProductName returns name the way it is stored, names are compared in this form
+func normalizeProductName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// claimProductName locks the name until the end of transaction and fails with ErrProductExists when another product
+// than exceptID has it, new products pass 0. Names are not unique in the table as older duplicates exist,
+// so the lock stands in for a constraint.
+func claimProductName(tx *sql.Tx, name string, exceptID int) error {
+	name = normalizeProductName(name)
+	if name == "" {
+		return ErrEmptyProductName
+	}
+	_, err := tx.Exec(`SELECT pg_advisory_xact_lock(hashtext($1));`, name)
+	if err != nil {
+		return errors.Wrap(err, "While locking product name")
+	}
+	var exists bool
+	err = tx.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM products WHERE name=$1 AND id<>$2);
+	`, name, exceptID).Scan(&exists)
+	if err != nil {
+		return errors.Wrap(err, "While querying for product by name")
+	}
+	if exists {
+		return ErrProductExists
+	}
+	return nil
+}
+
+// CreateProductWithPortions inserts product together with its portions and barcodes, either all of them are created or none
+func CreateProductWithPortions(db *sql.DB, product Product, portions []Portion, barcodes []string) (*Product, []Portion, error) {
+	var created *Product
+	var createdPortions []Portion
+	err := WithTx(db, func(tx *sql.Tx) error {
+		err := claimProductName(tx, product.Name, 0)
+		if err != nil {
+			return err
+		}
+		created, createdPortions, err = InsertProductWithPortions(tx, product, portions, barcodes)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return created, createdPortions, nil
+}
+
+// InsertProductWithPortions inserts product, its portions and barcodes using given transaction
+// and records the initial revision made by the creator
+func InsertProductWithPortions(tx *sql.Tx, product Product, portions []Portion, barcodes []string) (*Product, []Portion, error) {
+	created, err := CreateProduct(tx, product)
+	if err != nil {
+		return nil, nil, err
+	}
+	createdPortions := []Portion{}
+	for _, portion := range portions {
+		portion.ProductID = created.ID
+		dbPortion, err := CreatePortion(tx, portion)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "While creating portion for product")
+		}
+		createdPortions = append(createdPortions, *dbPortion)
+	}
+	for _, barcode := range barcodes {
+		err = CreateBarcode(tx, barcode, created.ID)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "While creating barcode for product")
+		}
+	}
+	_, err = recordRevision(tx, created.ID, created.Creator, RevisionCreate, &ProductSnapshot{Portions: []Portion{}})
+	if err != nil {
+		return nil, nil, err
+	}
+	return created, createdPortions, nil
+}
+
+func GetProductById(db DBTX, id int) (*Product, error) {
 	rows, err := db.Query(`
-		SELECT * FROM products WHERE id=$1;
+		SELECT `+productColumns+` FROM products WHERE id=$1;
 	`, id)
 	if err != nil {
 		return nil, errors.Wrap(err, "While querying for product by name")
@@ -64,131 +172,241 @@ func GetProductById(db *sql.DB, id int) (*Product, error) {
 	prods := []Product{}
 	for rows.Next() {
 		prod := Product{}
-		err := rows.Scan(&prod.ID, &prod.Creator, &prod.Name, &prod.Description)
+		err := prod.scanRow(rows)
 		if err != nil {
 			return nil, err
 		}
 		prods = append(prods, prod)
 	}
+	if len(prods) == 0 {
+		return nil, ErrNotFound
+	}
 	return &prods[0], nil
 }
 
-func GetProductsByName(db *sql.DB, name string, pagination Pagination) (*[]Product, *Pagination, error) {
+// ProductNameExists checks if there is a product with exactly the same (lowercased) name
+func ProductNameExists(db DBTX, name string) (bool, error) {
+	var exists bool
+	err := db.QueryRow(`
+		SELECT EXISTS (SELECT 1 FROM products WHERE name=$1);
+	`, normalizeProductName(name)).Scan(&exists)
+	if err != nil {
+		return false, errors.Wrap(err, "While querying for product by name")
+	}
+	return exists, nil
+}
+
+// ScoredProduct struct is used to represent product found by search together with its rank
+type ScoredProduct struct {
+	Product
+	Score     float64 `json:"score"`
+	Favourite bool    `json:"favourite"`
+}
+
+// productSearch matches products by full text search, trigram similarity or a plain substring of the name,
+// strpos is used instead of LIKE so % and _ typed by the user are not wildcards.
+// Rank combines relevance with net vote score and with how often the user logged the product.
+// Deleted products are never matched.
+// When $3 is true only users favourites are matched.
+const productSearch = `
+	FROM products
+	CROSS JOIN (SELECT plainto_tsquery('simple', $1) AS tsq, $1::text AS term) AS query
+	LEFT JOIN (
+		SELECT product_id, SUM(vote) AS net FROM votes GROUP BY product_id
+	) AS rating ON rating.product_id = products.id
+	LEFT JOIN (
+		SELECT product_id, COUNT(*) AS uses FROM entries WHERE user_id=$2 GROUP BY product_id
+	) AS usage ON usage.product_id = products.id
+	LEFT JOIN favourites ON favourites.product_id = products.id AND favourites.user_id=$2
+	WHERE NOT products.deleted AND (NOT $3 OR favourites.user_id IS NOT NULL) AND (
+		query.term = ''
+		OR to_tsvector('simple', products.name) @@ query.tsq
+		OR products.name % query.term
+		OR query.term <% products.name
+		OR strpos(products.name, query.term) > 0
+	)
+`
+
+const productSearchScore = `round((
+	ts_rank(to_tsvector('simple', products.name), query.tsq)
+	+ word_similarity(query.term, products.name)
+	+ similarity(products.name, query.term)
+	+ 0.2 * ln(1 + COALESCE(usage.uses, 0))
+	+ 0.1 * sign(COALESCE(rating.net, 0)) * ln(1 + abs(COALESCE(rating.net, 0)))
+)::numeric, 6)`
+
+func GetProductsByName(db *sql.DB, name string, userID int, favouritesOnly bool, pagination Pagination) (*[]ScoredProduct, *Pagination, error) {
+	term := strings.ToLower(strings.TrimSpace(name))
+	var lastScore float64
+	var lastName string
+	var lastID int
+	keyset, err := pagination.CursorKeys(&lastScore, &lastName, &lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	limit := pagination.Limit()
 	rows, err := db.Query(`
-		SELECT * FROM products WHERE name LIKE $1 ORDER BY name, id ASC LIMIT $2 OFFSET $3;;
-	`, "%"+strings.ToLower(name)+"%", pagination.ItemsPerPage, pagination.ItemsPerPage*pagination.Page)
+		SELECT `+productColumns+`, score, favourite FROM (
+			SELECT
+				`+qualifiedProductColumns+`,
+				`+productSearchScore+` AS score,
+				favourites.user_id IS NOT NULL AS favourite
+			`+productSearch+`
+		) AS ranked
+		WHERE NOT $6 OR score < $7 OR (score = $7 AND (name, id) > ($8, $9))
+		ORDER BY score DESC, name, id ASC LIMIT $4 OFFSET $5;
+	`, term, userID, favouritesOnly, limit+1, pagination.Offset(), keyset, lastScore, lastName, lastID)
 	if err != nil {
 		return nil, nil, errors.Wrap(err, "While querying for product by name")
 	}
 	defer rows.Close()
-	prods := []Product{}
+	prods := []ScoredProduct{}
 	for rows.Next() {
-		prod := Product{}
-		err := rows.Scan(&prod.ID, &prod.Creator, &prod.Name, &prod.Description)
+		prod := ScoredProduct{}
+		err := prod.scanRow(rows, &prod.Score, &prod.Favourite)
 		if err != nil {
 			return nil, nil, err
 		}
 		prods = append(prods, prod)
 	}
 	var count int
-	row := db.QueryRow("SELECT COUNT(*) FROM products")
+	row := db.QueryRow(`SELECT COUNT(*) `+productSearch, term, userID, favouritesOnly)
 	err = row.Scan(&count)
 	if err != nil {
 		return nil, nil, err
 	}
-	maxPage := int(math.Ceil(float64(count)/float64(pagination.ItemsPerPage)) - 1)
-	newPagination := Pagination{
-		ItemsPerPage: pagination.ItemsPerPage,
-		Page:         pagination.Page,
-		MaxPage:      maxPage,
+	more := len(prods) > limit
+	if !more {
+		return &prods, pagination.Result(count, false), nil
 	}
-	return &prods, &newPagination, nil
+	prods = prods[:limit]
+	last := prods[limit-1]
+	return &prods, pagination.Result(count, true, last.Score, last.Name, last.ID), nil
 }
 
 func GetProductsByCreatorID(db *sql.DB, id int, pagination Pagination) (*[]Product, *Pagination, error) {
+	var lastName string
+	var lastID int
+	keyset, err := pagination.CursorKeys(&lastName, &lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	limit := pagination.Limit()
 	rows, err := db.Query(`
-		SELECT * FROM products WHERE creator=$1 ORDER BY name, id ASC LIMIT $2 OFFSET $3;
-	`, id, pagination.ItemsPerPage, pagination.ItemsPerPage*pagination.Page)
+		SELECT `+productColumns+` FROM products
+		WHERE creator=$1 AND (NOT $4 OR (name, id) > ($5, $6))
+		ORDER BY name, id ASC LIMIT $2 OFFSET $3;
+	`, id, limit+1, pagination.Offset(), keyset, lastName, lastID)
 	if err != nil {
-		return nil, nil, errors.Wrap(err, "While querying for product by name")
+		return nil, nil, errors.Wrap(err, "While querying for product by creator")
 	}
 	defer rows.Close()
 	prods := []Product{}
 	for rows.Next() {
 		prod := Product{}
-		err := rows.Scan(&prod.ID, &prod.Creator, &prod.Name, &prod.Description)
+		err := prod.scanRow(rows)
 		if err != nil {
 			return nil, nil, err
 		}
 		prods = append(prods, prod)
 	}
 	var count int
-	row := db.QueryRow("SELECT COUNT(*) FROM products")
+	row := db.QueryRow("SELECT COUNT(*) FROM products WHERE creator=$1", id)
 	err = row.Scan(&count)
 	if err != nil {
 		return nil, nil, err
 	}
-	maxPage := int(math.Ceil(float64(count)/float64(pagination.ItemsPerPage)) - 1)
-	newPagination := Pagination{
-		ItemsPerPage: pagination.ItemsPerPage,
-		Page:         pagination.Page,
-		MaxPage:      maxPage,
+	more := len(prods) > limit
+	if !more {
+		return &prods, pagination.Result(count, false), nil
 	}
-	return &prods, &newPagination, nil
+	prods = prods[:limit]
+	last := prods[limit-1]
+	return &prods, pagination.Result(count, true, last.Name, last.ID), nil
 }
 
-func DeleteProduct(db *sql.DB, id int) error {
-	rows, err := db.Query(`
-		DELETE FROM entries WHERE product_id=$1;
-	`, id)
+// DeleteProduct hides product from search, it keeps resolving for entries, recipes and saved meals which use it
+func DeleteProduct(db *sql.DB, id, userID int) error {
+	result, err := db.Exec(`
+		UPDATE products SET deleted=true, deleted_by=$2, deleted_at=now() WHERE id=$1 AND NOT deleted;
+	`, id, userID)
 	if err != nil {
 		return errors.Wrap(err, "While deleting product")
 	}
-	defer rows.Close()
-	rows, err = db.Query(`
-		DELETE FROM portions WHERE product_id=$1;
-	`, id)
+	affected, err := result.RowsAffected()
 	if err != nil {
-		return errors.Wrap(err, "While deleting product")
+		return err
 	}
-	defer rows.Close()
-	rows, err = db.Query(`
-		DELETE FROM votes WHERE product_id=$1; 
+	if affected != 1 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func RestoreProduct(db *sql.DB, id int) error {
+	result, err := db.Exec(`
+		UPDATE products SET deleted=false, deleted_by=NULL, deleted_at=NULL WHERE id=$1 AND deleted;
 	`, id)
 	if err != nil {
-		return errors.Wrap(err, "While deleting product")
+		return errors.Wrap(err, "While restoring product")
 	}
-	defer rows.Close()
-	rows, err = db.Query(`
-		DELETE FROM products WHERE id=$1; 
-	`, id)
+	affected, err := result.RowsAffected()
 	if err != nil {
-		return errors.Wrap(err, "While deleting product")
+		return err
+	}
+	if affected != 1 {
+		return ErrNotFound
 	}
-	defer rows.Close()
-
 	return nil
 }
 
-func UpdateProduct(db *sql.DB, id int, new Product) (*Product, error) {
-	rows, err := db.Query(`
-		UPDATE products SET name=$2 WHERE id=$1 RETURNING *;;
-	`, id, new.Name)
-	if err != nil {
-		return nil, errors.Wrap(err, "While updating product")
-	}
-	defer rows.Close()
-	prods := []Product{}
-	for rows.Next() {
-		prod := Product{}
-		err := rows.Scan(&prod.ID, &prod.Creator, &prod.Name, &prod.Description)
+// UpdateProduct changes name of the product and records the change as a revision made by given user.
+// The name can't be blank or taken by another product, deleted products can't be changed.
+func UpdateProduct(db *sql.DB, id int, new Product, userID int) (*Product, error) {
+	var updated *Product
+	err := WithTx(db, func(tx *sql.Tx) error {
+		before, err := snapshotProduct(tx, id)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		prods = append(prods, prod)
-	}
-	if len(prods) != 1 {
-		return nil, errors.New("Duplicate products with same name in db")
+		var deleted bool
+		err = tx.QueryRow(`SELECT deleted FROM products WHERE id=$1;`, id).Scan(&deleted)
+		if err != nil {
+			return errors.Wrap(err, "While querying for product")
+		}
+		if deleted {
+			return ErrProductDeleted
+		}
+		err = claimProductName(tx, new.Name, id)
+		if err != nil {
+			return err
+		}
+		rows, err := tx.Query(`
+			UPDATE products SET name=$2 WHERE id=$1 RETURNING `+productColumns+`;
+		`, id, normalizeProductName(new.Name))
+		if err != nil {
+			return errors.Wrap(err, "While updating product")
+		}
+		prods := []Product{}
+		for rows.Next() {
+			prod := Product{}
+			err := prod.scanRow(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			prods = append(prods, prod)
+		}
+		rows.Close()
+		if len(prods) != 1 {
+			return errors.New("Duplicate products with same name in db")
+		}
+		updated = &prods[0]
+		_, err = recordRevision(tx, id, userID, RevisionUpdate, before)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
-	return &prods[0], nil
+	return updated, nil
 }
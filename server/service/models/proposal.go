@@ -0,0 +1,336 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type ProposalStatus string
+
+const (
+	ProposalPending  ProposalStatus = "pending"
+	ProposalApproved ProposalStatus = "approved"
+	ProposalRejected ProposalStatus = "rejected"
+)
+
+// ProposedPortions are portions proposed for a product, portion with zero ID is added, others replace existing ones
+type ProposedPortions []Portion
+
+// Value stores proposed portions as jsonb
+func (p ProposedPortions) Value() (driver.Value, error) {
+	if p == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal(p)
+}
+
+// Scan reads proposed portions from jsonb column
+func (p *ProposedPortions) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*p = ProposedPortions{}
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("Invalid proposed portions type")
+	}
+	out := ProposedPortions{}
+	err := json.Unmarshal(data, &out)
+	if err != nil {
+		return errors.Wrap(err, "While parsing proposed portions")
+	}
+	*p = out
+	return nil
+}
+
+// Proposal struct is used to represent correction of a product suggested by user and reviewed by moderator.
+// Nil name or description is left unchanged.
+type Proposal struct {
+	ID          int              `json:"id"`
+	ProductID   int              `json:"productID"`
+	UserID      int              `json:"userID"`
+	Name        *string          `json:"name,omitempty"`
+	Description *string          `json:"description,omitempty"`
+	Portions    ProposedPortions `json:"portions"`
+	Comment     string           `json:"comment"`
+	Status      ProposalStatus   `json:"status"`
+	ModeratorID int              `json:"moderatorID,omitempty"`
+	ReviewNote  string           `json:"reviewNote,omitempty"`
+	CreatedAt   time.Time        `json:"createdAt"`
+	ReviewedAt  *time.Time       `json:"reviewedAt,omitempty"`
+}
+
+const proposalColumns = `id, product_id, user_id, name, description, portions, comment, status, moderator_id, review_note, created_at, reviewed_at`
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func (proposal *Proposal) scanRow(row rowScanner) error {
+	var name, description sql.NullString
+	var moderatorID sql.NullInt64
+	var reviewedAt sql.NullTime
+	err := row.Scan(
+		&proposal.ID,
+		&proposal.ProductID,
+		&proposal.UserID,
+		&name,
+		&description,
+		&proposal.Portions,
+		&proposal.Comment,
+		&proposal.Status,
+		&moderatorID,
+		&proposal.ReviewNote,
+		&proposal.CreatedAt,
+		&reviewedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "While scaning row")
+	}
+	proposal.Name = nil
+	if name.Valid {
+		proposal.Name = &name.String
+	}
+	proposal.Description = nil
+	if description.Valid {
+		proposal.Description = &description.String
+	}
+	proposal.ModeratorID = int(moderatorID.Int64)
+	proposal.ReviewedAt = nil
+	if reviewedAt.Valid {
+		proposal.ReviewedAt = &reviewedAt.Time
+	}
+	return nil
+}
+
+func nullableString(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+func CreateProposal(db *sql.DB, proposal Proposal) (*Proposal, error) {
+	if proposal.Name != nil {
+		name := strings.ToLower(strings.TrimSpace(*proposal.Name))
+		proposal.Name = &name
+	}
+	created := &Proposal{}
+	err := created.scanRow(db.QueryRow(`
+		INSERT INTO proposals (product_id, user_id, name, description, portions, comment)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING `+proposalColumns+`;
+	`, proposal.ProductID, proposal.UserID, nullableString(proposal.Name), nullableString(proposal.Description),
+		proposal.Portions, proposal.Comment))
+	if err != nil {
+		return nil, errors.Wrap(err, "While inserting proposal")
+	}
+	return created, nil
+}
+
+func GetProposal(db *sql.DB, id int) (*Proposal, error) {
+	proposal := &Proposal{}
+	err := proposal.scanRow(db.QueryRow(`
+		SELECT `+proposalColumns+` FROM proposals WHERE id=$1;
+	`, id))
+	if errors.Cause(err) == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return proposal, nil
+}
+
+// GetPendingProposals returns proposals waiting for review, the oldest first
+func GetPendingProposals(db *sql.DB, pagination Pagination) (*[]Proposal, *Pagination, error) {
+	var lastCreated time.Time
+	var lastID int
+	keyset, err := pagination.CursorKeys(&lastCreated, &lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	limit := pagination.Limit()
+	rows, err := db.Query(`
+		SELECT `+proposalColumns+` FROM proposals
+		WHERE status=$1 AND (NOT $4 OR (created_at, id) > ($5, $6))
+		ORDER BY created_at, id LIMIT $2 OFFSET $3;
+	`, ProposalPending, limit+1, pagination.Offset(), keyset, lastCreated, lastID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "While querying for proposals")
+	}
+	defer rows.Close()
+	proposals := []Proposal{}
+	for rows.Next() {
+		proposal := Proposal{}
+		err := proposal.scanRow(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		proposals = append(proposals, proposal)
+	}
+	var count int
+	row := db.QueryRow("SELECT COUNT(*) FROM proposals WHERE status=$1", ProposalPending)
+	err = row.Scan(&count)
+	if err != nil {
+		return nil, nil, err
+	}
+	more := len(proposals) > limit
+	if !more {
+		return &proposals, pagination.Result(count, false), nil
+	}
+	proposals = proposals[:limit]
+	last := proposals[limit-1]
+	return &proposals, pagination.Result(count, true, last.CreatedAt, last.ID), nil
+}
+
+// lockPendingProposal locks proposal row until the end of transaction, so it is reviewed only once
+func lockPendingProposal(tx *sql.Tx, id int) (*Proposal, error) {
+	proposal := &Proposal{}
+	err := proposal.scanRow(tx.QueryRow(`
+		SELECT `+proposalColumns+` FROM proposals WHERE id=$1 FOR UPDATE;
+	`, id))
+	if errors.Cause(err) == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if proposal.Status != ProposalPending {
+		return nil, errors.New("Proposal is already reviewed")
+	}
+	return proposal, nil
+}
+
+func reviewProposal(tx *sql.Tx, id, moderatorID int, status ProposalStatus, note string) (*Proposal, error) {
+	reviewed := &Proposal{}
+	err := reviewed.scanRow(tx.QueryRow(`
+		UPDATE proposals SET status=$2, moderator_id=$3, review_note=$4, reviewed_at=now()
+		WHERE id=$1
+		RETURNING `+proposalColumns+`;
+	`, id, status, moderatorID, note))
+	if err != nil {
+		return nil, errors.Wrap(err, "While updating proposal status")
+	}
+	return reviewed, nil
+}
+
+// ApproveProposal applies proposed changes to the product, records them as a revision made by the moderator,
+// recomputes recipes using the product and marks proposal approved in one transaction.
+// Proposed name is claimed again, ErrProductExists is returned when another product took it since the proposal was made.
+func ApproveProposal(db *sql.DB, id, moderatorID int, note string) (*Proposal, error) {
+	var reviewed *Proposal
+	err := WithTx(db, func(tx *sql.Tx) error {
+		proposal, err := lockPendingProposal(tx, id)
+		if err != nil {
+			return err
+		}
+		before, err := snapshotProduct(tx, proposal.ProductID)
+		if err != nil {
+			return err
+		}
+		if proposal.Name != nil {
+			err = claimProductName(tx, *proposal.Name, proposal.ProductID)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = tx.Exec(`
+			UPDATE products SET name=COALESCE($2, name), description=COALESCE($3, description) WHERE id=$1;
+		`, proposal.ProductID, nullableString(proposal.Name), nullableString(proposal.Description))
+		if err != nil {
+			return errors.Wrap(err, "While updating product")
+		}
+		for _, portion := range proposal.Portions {
+			portion.ProductID = proposal.ProductID
+			if portion.ID == 0 {
+				_, err = CreatePortion(tx, portion)
+				if err != nil {
+					return errors.Wrap(err, "While inserting portion")
+				}
+				continue
+			}
+			_, err = UpdatePortion(tx, portion)
+			if err != nil {
+				return errors.Wrap(err, "While updating proposed portion")
+			}
+		}
+		_, err = recordRevision(tx, proposal.ProductID, moderatorID, RevisionProposal, before)
+		if err != nil {
+			return err
+		}
+		if len(proposal.Portions) > 0 {
+			err = RefreshRecipesUsingProduct(tx, proposal.ProductID)
+			if err != nil {
+				return errors.Wrap(err, "While recomputing recipes")
+			}
+		}
+		reviewed, err = reviewProposal(tx, id, moderatorID, ProposalApproved, note)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reviewed, nil
+}
+
+func RejectProposal(db *sql.DB, id, moderatorID int, note string) (*Proposal, error) {
+	var reviewed *Proposal
+	err := WithTx(db, func(tx *sql.Tx) error {
+		_, err := lockPendingProposal(tx, id)
+		if err != nil {
+			return err
+		}
+		reviewed, err = reviewProposal(tx, id, moderatorID, ProposalRejected, note)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reviewed, nil
+}
+
+// ProposalChange struct is used to represent single difference between product and proposal
+type ProposalChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// DiffProposal lists changes proposal would make to the product with given portions
+func DiffProposal(product Product, portions []Portion, proposal Proposal) []ProposalChange {
+	changes := []ProposalChange{}
+	if proposal.Name != nil && *proposal.Name != product.Name {
+		changes = append(changes, ProposalChange{Field: "name", Before: product.Name, After: *proposal.Name})
+	}
+	if proposal.Description != nil && *proposal.Description != product.Description {
+		changes = append(changes, ProposalChange{Field: "description", Before: product.Description, After: *proposal.Description})
+	}
+	current := map[int]Portion{}
+	for _, portion := range portions {
+		current[portion.ID] = portion
+	}
+	for _, proposed := range proposal.Portions {
+		proposed.ProductID = product.ID
+		before, ok := current[proposed.ID]
+		if !ok {
+			changes = append(changes, ProposalChange{Field: "portion", Before: nil, After: proposed})
+			continue
+		}
+		beforeJSON, _ := json.Marshal(before)
+		afterJSON, _ := json.Marshal(proposed)
+		if string(beforeJSON) != string(afterJSON) {
+			changes = append(changes, ProposalChange{Field: "portion", Before: before, After: proposed})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,53 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDiffProposal(t *testing.T) {
+	text := func(s string) *string {
+		return &s
+	}
+	product := Product{ID: 1, Name: "apple", Description: "Fresh fruit"}
+	piece := Portion{ID: 10, ProductID: 1, Unit: "piece", Nutrition: Nutrition{Energy: 80}}
+	slice := Portion{ID: 11, ProductID: 1, Unit: "slice", Nutrition: Nutrition{Energy: 10}}
+	portions := []Portion{piece, slice}
+	changedPiece := piece
+	changedPiece.Energy = 95
+	newPortion := Portion{Unit: "100g", Nutrition: Nutrition{Energy: 52}}
+	cases := []struct {
+		name     string
+		proposal Proposal
+		fields   []string
+	}{
+		{"nothing proposed", Proposal{}, []string{}},
+		{"same values", Proposal{Name: text("apple"), Description: text("Fresh fruit"), Portions: ProposedPortions{piece}}, []string{}},
+		{"name", Proposal{Name: text("green apple")}, []string{"name"}},
+		{"description", Proposal{Description: text("")}, []string{"description"}},
+		{"changed portion", Proposal{Portions: ProposedPortions{changedPiece, slice}}, []string{"portion"}},
+		{"new portion", Proposal{Portions: ProposedPortions{newPortion}}, []string{"portion"}},
+		{"everything", Proposal{Name: text("pear"), Description: text("Juicy"), Portions: ProposedPortions{changedPiece, newPortion}}, []string{"name", "description", "portion", "portion"}},
+	}
+	for _, c := range cases {
+		changes := DiffProposal(product, portions, c.proposal)
+		fields := []string{}
+		for _, change := range changes {
+			fields = append(fields, change.Field)
+		}
+		if !reflect.DeepEqual(fields, c.fields) {
+			t.Errorf("%s: expected changes of %v, got %+v", c.name, c.fields, changes)
+		}
+	}
+
+	changes := DiffProposal(product, portions, Proposal{Portions: ProposedPortions{changedPiece, newPortion}})
+	if len(changes) != 2 {
+		t.Fatalf("Expected changed and new portion, got %+v", changes)
+	}
+	if !reflect.DeepEqual(changes[0].Before, piece) || !reflect.DeepEqual(changes[0].After, changedPiece) {
+		t.Errorf("Expected changed portion with its previous values, got %+v", changes[0])
+	}
+	if added, ok := changes[1].After.(Portion); changes[1].Before != nil || !ok || added.ProductID != product.ID {
+		t.Errorf("Expected new portion of the product without previous values, got %+v", changes[1])
+	}
+}
@@ -0,0 +1,285 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// Units of portions derived for every recipe
+const (
+	RecipeServingUnit = "serving"
+	RecipeWholeUnit   = "whole recipe"
+)
+
+// ErrDeletedIngredient is returned when a recipe is created from a missing or deleted product
+var ErrDeletedIngredient = errors.New("Ingredient product does not exist or was deleted")
+
+// Ingredient struct is used to represent quantity of products portion used in a recipe
+type Ingredient struct {
+	ID        int     `json:"id"`
+	RecipeID  int     `json:"recipeID"`
+	ProductID int     `json:"productID"`
+	PortionID int     `json:"portionID"`
+	Quantity  float64 `json:"quantity"`
+}
+
+const ingredientColumns = `id, recipe_id, product_id, portion_id, quantity`
+
+func (ingredient *Ingredient) scanRow(rows *sql.Rows) error {
+	err := rows.Scan(
+		&ingredient.ID,
+		&ingredient.RecipeID,
+		&ingredient.ProductID,
+		&ingredient.PortionID,
+		&ingredient.Quantity,
+	)
+	if err != nil {
+		return errors.Wrap(err, "While scaning row")
+	}
+	return nil
+}
+
+func CreateIngredient(db DBTX, ingredient Ingredient) (*Ingredient, error) {
+	rows, err := db.Query(`
+		INSERT INTO ingredients (recipe_id, product_id, portion_id, quantity)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+ingredientColumns+`;
+	`, ingredient.RecipeID, ingredient.ProductID, ingredient.PortionID, ingredient.Quantity)
+	if err != nil {
+		return nil, errors.Wrap(err, "Invalid insert operation")
+	}
+	defer rows.Close()
+	ingredients := []Ingredient{}
+	for rows.Next() {
+		ingredient := Ingredient{}
+		err := ingredient.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		ingredients = append(ingredients, ingredient)
+	}
+	if len(ingredients) != 1 {
+		return nil, errors.New("Invalid return of insert operation")
+	}
+	return &ingredients[0], nil
+}
+
+// CreateRecipe inserts recipe product with its ingredients and derives its portions in one transaction.
+// Ingredient products are locked, so they can't be deleted before the recipe is created.
+func CreateRecipe(db *sql.DB, recipe Product, ingredients []Ingredient) (*Product, []Ingredient, error) {
+	var created *Product
+	createdIngredients := []Ingredient{}
+	err := WithTx(db, func(tx *sql.Tx) error {
+		err := claimProductName(tx, recipe.Name, 0)
+		if err != nil {
+			return err
+		}
+		err = lockIngredientProducts(tx, ingredients)
+		if err != nil {
+			return err
+		}
+		recipe.Recipe = true
+		created, err = CreateProduct(tx, recipe)
+		if err != nil {
+			return err
+		}
+		for _, ingredient := range ingredients {
+			ingredient.RecipeID = created.ID
+			dbIngredient, err := CreateIngredient(tx, ingredient)
+			if err != nil {
+				return errors.Wrap(err, "While creating ingredient")
+			}
+			createdIngredients = append(createdIngredients, *dbIngredient)
+		}
+		err = RefreshRecipe(tx, created.ID)
+		if err != nil {
+			return errors.Wrap(err, "While computing recipe portions")
+		}
+		_, err = recordRevision(tx, created.ID, created.Creator, RevisionCreate, &ProductSnapshot{Portions: []Portion{}})
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return created, createdIngredients, nil
+}
+
+// lockIngredientProducts locks products of the ingredients, ErrDeletedIngredient is returned when any is missing or deleted
+func lockIngredientProducts(tx *sql.Tx, ingredients []Ingredient) error {
+	seen := map[int]bool{}
+	ids := []int{}
+	for _, ingredient := range ingredients {
+		if !seen[ingredient.ProductID] {
+			seen[ingredient.ProductID] = true
+			ids = append(ids, ingredient.ProductID)
+		}
+	}
+	var found int
+	err := tx.QueryRow(`
+		SELECT COUNT(*) FROM (
+			SELECT id FROM products WHERE id = ANY($1) AND NOT deleted FOR SHARE
+		) AS usable;
+	`, pq.Array(ids)).Scan(&found)
+	if err != nil {
+		return errors.Wrap(err, "While locking ingredient products")
+	}
+	if found != len(ids) {
+		return ErrDeletedIngredient
+	}
+	return nil
+}
+
+func GetRecipesIngredients(db DBTX, recipeID int) ([]Ingredient, error) {
+	rows, err := db.Query(`
+		SELECT `+ingredientColumns+` FROM ingredients WHERE recipe_id=$1 ORDER BY id;
+	`, recipeID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ingredients := []Ingredient{}
+	for rows.Next() {
+		ingredient := Ingredient{}
+		err := ingredient.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		ingredients = append(ingredients, ingredient)
+	}
+	return ingredients, nil
+}
+
+// GetRecipesUsingProduct returns ids of recipes which have given product as an ingredient
+func GetRecipesUsingProduct(db DBTX, productID int) ([]int, error) {
+	rows, err := db.Query(`
+		SELECT DISTINCT recipe_id FROM ingredients WHERE product_id=$1;
+	`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []int{}
+	for rows.Next() {
+		var id int
+		err := rows.Scan(&id)
+		if err != nil {
+			return nil, errors.Wrap(err, "While scaning row")
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// DeriveRecipePortions computes serving and whole recipe portions, keyed by their unit, from ingredients
+// and portions they are measured in, given in the same order
+func DeriveRecipePortions(yield float64, ingredients []Ingredient, portions []Portion) map[string]Portion {
+	total := Nutrition{}
+	// weight of the whole recipe is known only when every ingredient portion is measured in grams
+	weight := 0.0
+	weighed := len(ingredients) > 0
+	for i, ingredient := range ingredients {
+		portion := portions[i]
+		total = total.Add(portion.Nutrition.Scale(ingredient.Quantity))
+		if portion.BaseUnit != BaseGram || portion.Amount <= 0 {
+			weighed = false
+		}
+		weight += portion.Amount * ingredient.Quantity
+	}
+	servings := yield
+	if servings <= 0 {
+		servings = 1
+	}
+	derived := map[string]Portion{
+		RecipeServingUnit: {Unit: RecipeServingUnit, Nutrition: total.Scale(1 / servings)},
+		RecipeWholeUnit:   {Unit: RecipeWholeUnit, Nutrition: total},
+	}
+	if weighed {
+		derived[RecipeServingUnit] = Portion{Unit: RecipeServingUnit, Amount: weight / servings, BaseUnit: BaseGram, Nutrition: total.Scale(1 / servings)}
+		derived[RecipeWholeUnit] = Portion{Unit: RecipeWholeUnit, Amount: weight, BaseUnit: BaseGram, Nutrition: total}
+	}
+	return derived
+}
+
+// RefreshRecipe recomputes portions of the recipe from its ingredients.
+// Portions are updated in place, so entries logged with them keep pointing at the same rows.
+func RefreshRecipe(db DBTX, recipeID int) error {
+	return refreshRecipe(db, recipeID, map[int]bool{})
+}
+
+// RefreshRecipesUsingProduct recomputes every recipe which has given product as an ingredient
+func RefreshRecipesUsingProduct(db DBTX, productID int) error {
+	return refreshRecipesUsingProduct(db, productID, map[int]bool{})
+}
+
+func refreshRecipesUsingProduct(db DBTX, productID int, visited map[int]bool) error {
+	recipeIDs, err := GetRecipesUsingProduct(db, productID)
+	if err != nil {
+		return errors.Wrap(err, "While getting recipes using product")
+	}
+	for _, recipeID := range recipeIDs {
+		err = refreshRecipe(db, recipeID, visited)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func refreshRecipe(db DBTX, recipeID int, visited map[int]bool) error {
+	if visited[recipeID] {
+		return nil
+	}
+	visited[recipeID] = true
+	recipe, err := GetProductById(db, recipeID)
+	if err != nil {
+		return errors.Wrap(err, "While getting recipe")
+	}
+	if !recipe.Recipe {
+		return nil
+	}
+	ingredients, err := GetRecipesIngredients(db, recipeID)
+	if err != nil {
+		return errors.Wrap(err, "While getting recipe ingredients")
+	}
+	ingredientPortions := []Portion{}
+	for _, ingredient := range ingredients {
+		portion, err := GetPortion(db, ingredient.PortionID)
+		if err != nil {
+			return errors.Wrap(err, "While getting ingredient portion")
+		}
+		ingredientPortions = append(ingredientPortions, *portion)
+	}
+	derived := DeriveRecipePortions(recipe.Yield, ingredients, ingredientPortions)
+	portions, err := GetProductsPortions(db, recipeID)
+	if err != nil {
+		return errors.Wrap(err, "While getting recipe portions")
+	}
+	for _, portion := range portions {
+		derivedPortion, ok := derived[portion.Unit]
+		if !ok {
+			continue
+		}
+		portion.Nutrition = derivedPortion.Nutrition
+		portion.Amount = derivedPortion.Amount
+		portion.BaseUnit = derivedPortion.BaseUnit
+		_, err = UpdatePortion(db, portion)
+		if err != nil {
+			return errors.Wrap(err, "While updating recipe portion")
+		}
+		delete(derived, portion.Unit)
+	}
+	for _, unit := range []string{RecipeServingUnit, RecipeWholeUnit} {
+		portion, ok := derived[unit]
+		if !ok {
+			continue
+		}
+		portion.ProductID = recipeID
+		_, err = CreatePortion(db, portion)
+		if err != nil {
+			return errors.Wrap(err, "While creating recipe portion")
+		}
+	}
+	return refreshRecipesUsingProduct(db, recipeID, visited)
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDeriveRecipePortions(t *testing.T) {
+	flour := Portion{Unit: "100g", Amount: 100, BaseUnit: BaseGram, Nutrition: Nutrition{Energy: 350, Protein: 10}}
+	milk := Portion{Unit: "100ml", Amount: 100, BaseUnit: BaseMillilitre, Nutrition: Nutrition{Energy: 60, Protein: 3}}
+	egg := Portion{Unit: "piece", Nutrition: Nutrition{Energy: 80, Protein: 6}}
+	cases := []struct {
+		name        string
+		yield       float64
+		ingredients []Ingredient
+		portions    []Portion
+		serving     Portion
+		whole       Portion
+	}{
+		{
+			name:        "weighed ingredients",
+			yield:       4,
+			ingredients: []Ingredient{{Quantity: 2}, {Quantity: 1}},
+			portions:    []Portion{flour, flour},
+			serving:     Portion{Amount: 75, BaseUnit: BaseGram, Nutrition: Nutrition{Energy: 262.5, Protein: 7.5}},
+			whole:       Portion{Amount: 300, BaseUnit: BaseGram, Nutrition: Nutrition{Energy: 1050, Protein: 30}},
+		},
+		{
+			name:        "millilitres make weight unknown",
+			yield:       2,
+			ingredients: []Ingredient{{Quantity: 2}, {Quantity: 3}},
+			portions:    []Portion{flour, milk},
+			serving:     Portion{Nutrition: Nutrition{Energy: 440, Protein: 14.5}},
+			whole:       Portion{Nutrition: Nutrition{Energy: 880, Protein: 29}},
+		},
+		{
+			name:        "pieces make weight unknown",
+			yield:       1,
+			ingredients: []Ingredient{{Quantity: 1}, {Quantity: 2}},
+			portions:    []Portion{flour, egg},
+			serving:     Portion{Nutrition: Nutrition{Energy: 510, Protein: 22}},
+			whole:       Portion{Nutrition: Nutrition{Energy: 510, Protein: 22}},
+		},
+		{
+			name:        "non positive yield is one serving",
+			yield:       0,
+			ingredients: []Ingredient{{Quantity: 1}},
+			portions:    []Portion{flour},
+			serving:     Portion{Amount: 100, BaseUnit: BaseGram, Nutrition: Nutrition{Energy: 350, Protein: 10}},
+			whole:       Portion{Amount: 100, BaseUnit: BaseGram, Nutrition: Nutrition{Energy: 350, Protein: 10}},
+		},
+		{
+			name:    "no ingredients",
+			yield:   2,
+			serving: Portion{},
+			whole:   Portion{},
+		},
+	}
+	same := func(got, want Portion) bool {
+		return math.Abs(got.Amount-want.Amount) < 1e-9 && got.BaseUnit == want.BaseUnit &&
+			math.Abs(got.Energy-want.Energy) < 1e-9 && math.Abs(got.Protein-want.Protein) < 1e-9
+	}
+	for _, c := range cases {
+		derived := DeriveRecipePortions(c.yield, c.ingredients, c.portions)
+		if len(derived) != 2 {
+			t.Errorf("%s: expected serving and whole portions, got %v", c.name, derived)
+			continue
+		}
+		if serving := derived[RecipeServingUnit]; serving.Unit != RecipeServingUnit || !same(serving, c.serving) {
+			t.Errorf("%s: expected serving %+v, got %+v", c.name, c.serving, serving)
+		}
+		if whole := derived[RecipeWholeUnit]; whole.Unit != RecipeWholeUnit || !same(whole, c.whole) {
+			t.Errorf("%s: expected whole recipe %+v, got %+v", c.name, c.whole, whole)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+const (
+	RevisionCreate   = "create"
+	RevisionUpdate   = "update"
+	RevisionPortions = "portions"
+	RevisionProposal = "proposal"
+	RevisionRollback = "rollback"
+)
+
+// ProductSnapshot holds editable state of a product at some point in time
+type ProductSnapshot struct {
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Portions    []Portion `json:"portions"`
+}
+
+// Value stores snapshot as jsonb
+func (s ProductSnapshot) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+// Scan reads snapshot from jsonb column
+func (s *ProductSnapshot) Scan(src interface{}) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return errors.New("Invalid product snapshot type")
+	}
+	out := ProductSnapshot{}
+	err := json.Unmarshal(data, &out)
+	if err != nil {
+		return errors.Wrap(err, "While parsing product snapshot")
+	}
+	*s = out
+	return nil
+}
+
+// Revision struct is used to represent a single change of a product, revisions are never modified
+type Revision struct {
+	ID        int             `json:"id"`
+	ProductID int             `json:"productID"`
+	UserID    int             `json:"userID"`
+	Action    string          `json:"action"`
+	Before    ProductSnapshot `json:"before"`
+	After     ProductSnapshot `json:"after"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
+
+const revisionColumns = `id, product_id, user_id, action, before, after, created_at`
+
+func (revision *Revision) scanRow(row rowScanner) error {
+	err := row.Scan(
+		&revision.ID,
+		&revision.ProductID,
+		&revision.UserID,
+		&revision.Action,
+		&revision.Before,
+		&revision.After,
+		&revision.CreatedAt,
+	)
+	if err != nil {
+		return errors.Wrap(err, "While scaning row")
+	}
+	return nil
+}
+
+// snapshotProduct reads current state of the product and locks it until the end of transaction
+func snapshotProduct(tx *sql.Tx, productID int) (*ProductSnapshot, error) {
+	snapshot := &ProductSnapshot{Portions: []Portion{}}
+	var description sql.NullString
+	err := tx.QueryRow(`
+		SELECT name, description FROM products WHERE id=$1 FOR UPDATE;
+	`, productID).Scan(&snapshot.Name, &description)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "While querying for product")
+	}
+	snapshot.Description = description.String
+	rows, err := tx.Query(`
+		SELECT `+portionColumns+` FROM portions WHERE product_id=$1 ORDER BY id;
+	`, productID)
+	if err != nil {
+		return nil, errors.Wrap(err, "While querying for portions")
+	}
+	defer rows.Close()
+	for rows.Next() {
+		portion := Portion{}
+		err := portion.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshot.Portions = append(snapshot.Portions, portion)
+	}
+	return snapshot, nil
+}
+
+// recordRevision stores revision from given state to the current state of the product
+func recordRevision(tx *sql.Tx, productID, userID int, action string, before *ProductSnapshot) (*Revision, error) {
+	after, err := snapshotProduct(tx, productID)
+	if err != nil {
+		return nil, err
+	}
+	revision := &Revision{}
+	err = revision.scanRow(tx.QueryRow(`
+		INSERT INTO product_revisions (product_id, user_id, action, before, after)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+revisionColumns+`;
+	`, productID, userID, action, *before, *after))
+	if err != nil {
+		return nil, errors.Wrap(err, "While inserting revision")
+	}
+	return revision, nil
+}
+
+func GetRevision(db *sql.DB, id int) (*Revision, error) {
+	revision := &Revision{}
+	err := revision.scanRow(db.QueryRow(`
+		SELECT `+revisionColumns+` FROM product_revisions WHERE id=$1;
+	`, id))
+	if errors.Cause(err) == sql.ErrNoRows {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// GetProductsRevisions returns revisions of the product, the newest first
+func GetProductsRevisions(db *sql.DB, productID int, pagination Pagination) (*[]Revision, *Pagination, error) {
+	var lastID int
+	keyset, err := pagination.CursorKeys(&lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	limit := pagination.Limit()
+	rows, err := db.Query(`
+		SELECT `+revisionColumns+` FROM product_revisions
+		WHERE product_id=$1 AND (NOT $4 OR id < $5)
+		ORDER BY id DESC LIMIT $2 OFFSET $3;
+	`, productID, limit+1, pagination.Offset(), keyset, lastID)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "While querying for revisions")
+	}
+	defer rows.Close()
+	revisions := []Revision{}
+	for rows.Next() {
+		revision := Revision{}
+		err := revision.scanRow(rows)
+		if err != nil {
+			return nil, nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	var count int
+	row := db.QueryRow("SELECT COUNT(*) FROM product_revisions WHERE product_id=$1", productID)
+	err = row.Scan(&count)
+	if err != nil {
+		return nil, nil, err
+	}
+	more := len(revisions) > limit
+	if !more {
+		return &revisions, pagination.Result(count, false), nil
+	}
+	revisions = revisions[:limit]
+	return &revisions, pagination.Result(count, true, revisions[limit-1].ID), nil
+}
+
+// RollbackProduct restores the product to its state right after given revision and records it as a new revision.
+// Portions added since then are removed unless they are already logged or used by a recipe or saved meal.
+// Portions of the revision which belong to another product by now, e.g. after a merge, can't be restored
+// and are returned. Portions of a recipe are derived from its current ingredients instead of restored.
+// Deleted products have to be restored first. Recipes using the product are recomputed in the same transaction.
+func RollbackProduct(db *sql.DB, revisionID, userID int) (*Revision, []Portion, error) {
+	target, err := GetRevision(db, revisionID)
+	if err != nil {
+		return nil, nil, err
+	}
+	var revision *Revision
+	skipped := []Portion{}
+	err = WithTx(db, func(tx *sql.Tx) error {
+		before, err := snapshotProduct(tx, target.ProductID)
+		if err != nil {
+			return err
+		}
+		var recipe, deleted bool
+		err = tx.QueryRow(`
+			SELECT recipe, deleted FROM products WHERE id=$1;
+		`, target.ProductID).Scan(&recipe, &deleted)
+		if err != nil {
+			return errors.Wrap(err, "While querying for product")
+		}
+		if deleted {
+			return ErrProductDeleted
+		}
+		state := target.After
+		err = claimProductName(tx, state.Name, target.ProductID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`
+			UPDATE products SET name=$2, description=$3 WHERE id=$1;
+		`, target.ProductID, normalizeProductName(state.Name), state.Description)
+		if err != nil {
+			return errors.Wrap(err, "While restoring product")
+		}
+		if recipe {
+			err = RefreshRecipe(tx, target.ProductID)
+			if err != nil {
+				return errors.Wrap(err, "While computing recipe portions")
+			}
+			revision, err = recordRevision(tx, target.ProductID, userID, RevisionRollback, before)
+			return err
+		}
+		kept := []int64{}
+		for _, portion := range state.Portions {
+			kept = append(kept, int64(portion.ID))
+			result, err := tx.Exec(`
+				INSERT INTO portions (id, product_id, unit, amount, base_unit, energy, protein, carbohydrate, fat, fiber, sugar, sodium, micronutrients)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+				ON CONFLICT (id) DO UPDATE SET unit=$3, amount=$4, base_unit=$5, energy=$6, protein=$7, carbohydrate=$8, fat=$9,
+					fiber=$10, sugar=$11, sodium=$12, micronutrients=$13
+				WHERE portions.product_id=$2;
+			`, portion.ID, target.ProductID, portion.Unit, portion.Amount, portion.BaseUnit, portion.Energy, portion.Protein,
+				portion.Carbohydrate, portion.Fat, portion.Fiber, portion.Sugar, portion.Sodium, portion.Micronutrients)
+			if err != nil {
+				return errors.Wrap(err, "While restoring portion")
+			}
+			affected, err := result.RowsAffected()
+			if err != nil {
+				return err
+			}
+			// conflicting portion which is not updated has been moved to another product
+			if affected == 0 {
+				skipped = append(skipped, portion)
+			}
+		}
+		_, err = tx.Exec(`
+			DELETE FROM portions
+			WHERE product_id=$1 AND NOT (id = ANY($2))
+				AND NOT EXISTS (SELECT 1 FROM entries WHERE entries.portion_id = portions.id)
+				AND NOT EXISTS (SELECT 1 FROM ingredients WHERE ingredients.portion_id = portions.id)
+				AND NOT EXISTS (SELECT 1 FROM saved_meal_items WHERE saved_meal_items.portion_id = portions.id);
+		`, target.ProductID, pq.Array(kept))
+		if err != nil {
+			return errors.Wrap(err, "While removing portions added after revision")
+		}
+		revision, err = recordRevision(tx, target.ProductID, userID, RevisionRollback, before)
+		if err != nil {
+			return err
+		}
+		return RefreshRecipesUsingProduct(tx, target.ProductID)
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return revision, skipped, nil
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// SavedMeal struct is used to represent named bundle of products which user logs together
+type SavedMeal struct {
+	ID     int             `json:"id"`
+	UserID int             `json:"userID"`
+	Name   string          `json:"name"`
+	Items  []SavedMealItem `json:"items"`
+}
+
+type SavedMealItem struct {
+	ID        int     `json:"id"`
+	MealID    int     `json:"mealID"`
+	ProductID int     `json:"productID"`
+	PortionID int     `json:"portionID"`
+	Quantity  float64 `json:"quantity"`
+}
+
+const savedMealItemColumns = `id, meal_id, product_id, portion_id, quantity`
+
+func (item *SavedMealItem) scanRow(rows *sql.Rows) error {
+	err := rows.Scan(
+		&item.ID,
+		&item.MealID,
+		&item.ProductID,
+		&item.PortionID,
+		&item.Quantity,
+	)
+	if err != nil {
+		return errors.Wrap(err, "While scaning row")
+	}
+	return nil
+}
+
+// CreateSavedMeal inserts meal together with its items
+func CreateSavedMeal(db *sql.DB, meal SavedMeal) (*SavedMeal, error) {
+	var created SavedMeal
+	err := WithTx(db, func(tx *sql.Tx) error {
+		created = SavedMeal{UserID: meal.UserID, Name: strings.TrimSpace(meal.Name), Items: []SavedMealItem{}}
+		err := tx.QueryRow(`
+			INSERT INTO saved_meals (user_id, name)
+			VALUES ($1, $2)
+			RETURNING id;
+		`, created.UserID, created.Name).Scan(&created.ID)
+		if err != nil {
+			return err
+		}
+		for _, item := range meal.Items {
+			item.MealID = created.ID
+			err = tx.QueryRow(`
+				INSERT INTO saved_meal_items (meal_id, product_id, portion_id, quantity)
+				VALUES ($1, $2, $3, $4)
+				RETURNING id;
+			`, item.MealID, item.ProductID, item.PortionID, item.Quantity).Scan(&item.ID)
+			if err != nil {
+				return errors.Wrap(err, "While inserting saved meal item")
+			}
+			created.Items = append(created.Items, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func getSavedMealsItems(db *sql.DB, mealID int) ([]SavedMealItem, error) {
+	rows, err := db.Query(`
+		SELECT `+savedMealItemColumns+` FROM saved_meal_items WHERE meal_id=$1 ORDER BY id;
+	`, mealID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []SavedMealItem{}
+	for rows.Next() {
+		item := SavedMealItem{}
+		err := item.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+func GetSavedMeal(db *sql.DB, id int) (*SavedMeal, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, name FROM saved_meals WHERE id=$1;
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	meals := []SavedMeal{}
+	for rows.Next() {
+		meal := SavedMeal{}
+		err := rows.Scan(&meal.ID, &meal.UserID, &meal.Name)
+		if err != nil {
+			return nil, errors.Wrap(err, "While scaning row")
+		}
+		meals = append(meals, meal)
+	}
+	if len(meals) == 0 {
+		return nil, ErrNotFound
+	}
+	meal := meals[0]
+	meal.Items, err = getSavedMealsItems(db, meal.ID)
+	if err != nil {
+		return nil, errors.Wrap(err, "While getting saved meal items")
+	}
+	return &meal, nil
+}
+
+func GetUsersSavedMeals(db *sql.DB, userID int) ([]SavedMeal, error) {
+	rows, err := db.Query(`
+		SELECT id, user_id, name FROM saved_meals WHERE user_id=$1 ORDER BY name, id;
+	`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	meals := []SavedMeal{}
+	for rows.Next() {
+		meal := SavedMeal{}
+		err := rows.Scan(&meal.ID, &meal.UserID, &meal.Name)
+		if err != nil {
+			return nil, errors.Wrap(err, "While scaning row")
+		}
+		meals = append(meals, meal)
+	}
+	for i := range meals {
+		meals[i].Items, err = getSavedMealsItems(db, meals[i].ID)
+		if err != nil {
+			return nil, errors.Wrap(err, "While getting saved meal items")
+		}
+	}
+	return meals, nil
+}
+
+func DeleteSavedMeal(db *sql.DB, id int) error {
+	rows, err := db.Query(`
+		DELETE FROM saved_meals WHERE id=$1;
+	`, id)
+	if err != nil {
+		return errors.Wrap(err, "While deleting saved meal")
+	}
+	defer rows.Close()
+	return nil
+}
+
+// LogSavedMeal creates entry for every item of the meal, either all of them are created or none.
+// Items which are no longer valid entries, e.g. of a deleted product, fail with *ValidationError.
+func LogSavedMeal(db *sql.DB, meal SavedMeal, userID int, date time.Time, slot string) ([]Entry, error) {
+	entries := []Entry{}
+	today := time.Now()
+	err := WithTx(db, func(tx *sql.Tx) error {
+		for _, item := range meal.Items {
+			entry, err := insertValidEntry(tx, Entry{
+				UserID:    userID,
+				ProductID: item.ProductID,
+				PortionID: item.PortionID,
+				Quantity:  item.Quantity,
+				Date:      date,
+				Slot:      slot,
+			}, today)
+			if err != nil {
+				return err
+			}
+			entries = append(entries, *entry)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -0,0 +1,101 @@
+package models
+
+import (
+	"database/sql"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultSlots are used for users who did not define their own meal slots
+var DefaultSlots = []string{"breakfast", "lunch", "dinner", "snack"}
+
+const MaxSlots = 10
+
+// NormalizeSlot trims and lowercases slot name
+func NormalizeSlot(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+// GetUsersSlots returns users meal slot names in display order
+func GetUsersSlots(db DBTX, userID int) ([]string, error) {
+	rows, err := db.Query(`
+		SELECT name FROM meal_slots WHERE user_id=$1 ORDER BY position;
+	`, userID)
+	if err != nil {
+		return nil, errors.Wrap(err, "While querying for meal slots")
+	}
+	defer rows.Close()
+	slots := []string{}
+	for rows.Next() {
+		var name string
+		err := rows.Scan(&name)
+		if err != nil {
+			return nil, errors.Wrap(err, "While scaning row")
+		}
+		slots = append(slots, name)
+	}
+	if len(slots) == 0 {
+		return DefaultSlots, nil
+	}
+	return slots, nil
+}
+
+// SetUsersSlots replaces users meal slots, entries keep slot names they were logged with
+func SetUsersSlots(db *sql.DB, userID int, slots []string) error {
+	return WithTx(db, func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			DELETE FROM meal_slots WHERE user_id=$1;
+		`, userID)
+		if err != nil {
+			return errors.Wrap(err, "While deleting meal slots")
+		}
+		for position, name := range slots {
+			_, err = tx.Exec(`
+				INSERT INTO meal_slots (user_id, name, position)
+				VALUES ($1, $2, $3);
+			`, userID, name, position)
+			if err != nil {
+				return errors.Wrap(err, "While inserting meal slot")
+			}
+		}
+		return nil
+	})
+}
+
+// ValidateSlots normalizes slot names and checks if they are non empty and unique
+func ValidateSlots(slots []string) ([]string, error) {
+	if len(slots) == 0 {
+		return nil, errors.New("No meal slots provided")
+	}
+	if len(slots) > MaxSlots {
+		return nil, errors.New("Too many meal slots")
+	}
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, slot := range slots {
+		name := NormalizeSlot(slot)
+		if name == "" {
+			return nil, errors.New("Meal slot name cannot be empty")
+		}
+		if seen[name] {
+			return nil, errors.New("Duplicate meal slot name")
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	return normalized, nil
+}
+
+// HasSlot checks if slot is one of users slots, empty slot means entry is not assigned to any meal
+func HasSlot(slots []string, slot string) bool {
+	if slot == "" {
+		return true
+	}
+	for _, name := range slots {
+		if name == slot {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,76 @@
+package models
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ProductSummary struct is used to represent nutrition of one product eaten in a day
+type ProductSummary struct {
+	ProductID int    `json:"productID"`
+	Name      string `json:"name"`
+	Nutrition
+}
+
+// DaySummary struct is used to represent nutrition eaten in a day, broken down by product
+type DaySummary struct {
+	Date     time.Time        `json:"date"`
+	Total    Nutrition        `json:"total"`
+	Products []ProductSummary `json:"products"`
+}
+
+// GetUsersDailySummaries returns summaries of every day between from and to (inclusive) which has entries
+func GetUsersDailySummaries(db *sql.DB, userID int, from, to time.Time) ([]DaySummary, error) {
+	rows, err := db.Query(`
+		SELECT
+			entries.date,
+			products.id,
+			products.name,
+			SUM(entries.quantity * portions.energy),
+			SUM(entries.quantity * portions.protein),
+			SUM(entries.quantity * portions.carbohydrate),
+			SUM(entries.quantity * portions.fat),
+			SUM(entries.quantity * portions.fiber),
+			SUM(entries.quantity * portions.sugar),
+			SUM(entries.quantity * portions.sodium)
+		FROM entries
+		JOIN products ON products.id = entries.product_id
+		JOIN portions ON portions.id = entries.portion_id
+		WHERE entries.user_id=$1 AND entries.date BETWEEN $2 AND $3
+		GROUP BY entries.date, products.id, products.name
+		ORDER BY entries.date, products.name, products.id;
+	`, userID, from, to)
+	if err != nil {
+		return nil, errors.Wrap(err, "While querying for daily summaries")
+	}
+	defer rows.Close()
+	days := []DaySummary{}
+	for rows.Next() {
+		var date time.Time
+		prod := ProductSummary{}
+		err := rows.Scan(
+			&date,
+			&prod.ProductID,
+			&prod.Name,
+			&prod.Energy,
+			&prod.Protein,
+			&prod.Carbohydrate,
+			&prod.Fat,
+			&prod.Fiber,
+			&prod.Sugar,
+			&prod.Sodium,
+		)
+		if err != nil {
+			return nil, errors.Wrap(err, "While scaning row")
+		}
+		if len(days) == 0 || !days[len(days)-1].Date.Equal(date) {
+			days = append(days, DaySummary{Date: date, Products: []ProductSummary{}})
+		}
+		day := &days[len(days)-1]
+		day.Products = append(day.Products, prod)
+		day.Total = day.Total.Add(prod.Nutrition)
+	}
+	return days, rows.Err()
+}
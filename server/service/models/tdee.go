@@ -0,0 +1,104 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// EnergyPerKilogram is approximate energy (kcal) stored in one kilogram of body weight
+const EnergyPerKilogram = 7700
+
+// MinLoggedDays is the number of days with entries needed in a window to estimate expenditure
+const MinLoggedDays = 7
+
+// TDEE struct is used to represent estimated total daily energy expenditure with 95% confidence bounds
+type TDEE struct {
+	From          time.Time   `json:"from"`
+	To            time.Time   `json:"to"`
+	Estimate      float64     `json:"estimate"`
+	Lower         float64     `json:"lower"`
+	Upper         float64     `json:"upper"`
+	AverageIntake float64     `json:"averageIntake"`
+	WeightChange  float64     `json:"weightChange"`
+	LoggedDays    int         `json:"loggedDays"`
+	MissingDays   []time.Time `json:"missingDays"`
+}
+
+// trendAt returns trend of the last point on or before date
+func trendAt(points []TrendPoint, date time.Time) (float64, bool) {
+	value, found := 0.0, false
+	for _, point := range points {
+		if point.Date.After(date) {
+			break
+		}
+		value, found = point.Trend, true
+	}
+	return value, found
+}
+
+// EstimateTDEE estimates expenditure from energy eaten on logged days and weight trend change between from and to.
+// Intake is averaged over days from from up to, but excluding, to, so it covers the same number of days as the
+// weight change. Days in that range missing in logged are reported and left out of the average intake.
+func EstimateTDEE(intake map[time.Time]float64, logged []time.Time, points []TrendPoint, from, to time.Time) (*TDEE, error) {
+	from, to = Day(from), Day(to)
+	days := to.Sub(from).Hours() / 24
+	if days < 1 {
+		return nil, errors.New("Window too short")
+	}
+	isLogged := map[time.Time]bool{}
+	for _, date := range logged {
+		isLogged[Day(date)] = true
+	}
+	tdee := TDEE{From: from, To: to, MissingDays: []time.Time{}}
+	values := []float64{}
+	for day := from; day.Before(to); day = day.AddDate(0, 0, 1) {
+		if !isLogged[day] {
+			tdee.MissingDays = append(tdee.MissingDays, day)
+			continue
+		}
+		values = append(values, intake[day])
+	}
+	tdee.LoggedDays = len(values)
+	if tdee.LoggedDays < MinLoggedDays {
+		return nil, errors.New("Not enough logged days")
+	}
+	start, okStart := trendAt(points, from)
+	end, okEnd := trendAt(points, to)
+	if !okStart || !okEnd {
+		return nil, errors.New("Not enough weight measurements")
+	}
+	var sum float64
+	for _, value := range values {
+		sum += value
+	}
+	mean := sum / float64(len(values))
+	var squares float64
+	for _, value := range values {
+		squares += (value - mean) * (value - mean)
+	}
+	intakeError := math.Sqrt(squares/float64(len(values)-1)) / math.Sqrt(float64(len(values)))
+
+	var residuals float64
+	var measured int
+	for _, point := range points {
+		if point.Date.Before(from) || point.Date.After(to) {
+			continue
+		}
+		residuals += (point.Weight - point.Trend) * (point.Weight - point.Trend)
+		measured++
+	}
+	weightError := 0.0
+	if measured > 1 {
+		weightError = math.Sqrt(residuals/float64(measured-1)) * math.Sqrt2 * EnergyPerKilogram / days
+	}
+
+	tdee.AverageIntake = mean
+	tdee.WeightChange = end - start
+	tdee.Estimate = mean - tdee.WeightChange*EnergyPerKilogram/days
+	margin := 1.96 * math.Sqrt(intakeError*intakeError+weightError*weightError)
+	tdee.Lower = tdee.Estimate - margin
+	tdee.Upper = tdee.Estimate + margin
+	return &tdee, nil
+}
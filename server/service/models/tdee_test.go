@@ -0,0 +1,86 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEstimateTDEE(t *testing.T) {
+	from := time.Date(2024, time.April, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time {
+		return from.AddDate(0, 0, n)
+	}
+	// diary logs intake on every day in [0, days) except skipped ones, alternating between the given values
+	diary := func(days int, values []float64, skipped ...int) (map[time.Time]float64, []time.Time) {
+		intake := map[time.Time]float64{}
+		logged := []time.Time{}
+		skip := map[int]bool{}
+		for _, n := range skipped {
+			skip[n] = true
+		}
+		for n := 0; n < days; n++ {
+			if skip[n] {
+				continue
+			}
+			intake[day(n)] = values[n%len(values)]
+			logged = append(logged, day(n))
+		}
+		return intake, logged
+	}
+	trend := func(start, end float64, days int) []TrendPoint {
+		return []TrendPoint{
+			{Date: day(0), Weight: start, Trend: start},
+			{Date: day(days), Weight: end, Trend: end},
+		}
+	}
+	cases := []struct {
+		name     string
+		days     int
+		values   []float64
+		skipped  []int
+		points   []TrendPoint
+		estimate float64
+		margin   float64
+		missing  int
+		err      string
+	}{
+		{name: "window too short", days: 0, values: []float64{2000}, points: trend(80, 80, 0), err: "Window too short"},
+		{name: "too few logged days", days: 14, values: []float64{2000}, skipped: []int{0, 1, 2, 3, 4, 5, 6, 7}, points: trend(80, 80, 14), err: "Not enough logged days"},
+		{name: "no weight before window", days: 14, values: []float64{2000}, points: trend(80, 80, 14)[1:], err: "Not enough weight measurements"},
+		{name: "stable weight", days: 14, values: []float64{2000}, points: trend(80, 80, 14), estimate: 2000},
+		{name: "losing weight", days: 14, values: []float64{2000}, points: trend(80, 79, 14), estimate: 2000 + EnergyPerKilogram/14.0},
+		{name: "missing days left out", days: 14, values: []float64{2000}, skipped: []int{3, 4}, points: trend(80, 80, 14), estimate: 2000, missing: 2},
+		{
+			name:     "varying intake widens bounds",
+			days:     10,
+			values:   []float64{1800, 2200},
+			points:   trend(80, 80, 10),
+			estimate: 2000,
+			margin:   1.96 * math.Sqrt(400000.0/9) / math.Sqrt(10),
+		},
+	}
+	for _, c := range cases {
+		intake, logged := diary(c.days, c.values, c.skipped...)
+		tdee, err := EstimateTDEE(intake, logged, c.points, day(0), day(c.days))
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("%s: expected error %q, got %v", c.name, c.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+			continue
+		}
+		if math.Abs(tdee.Estimate-c.estimate) > 1e-6 {
+			t.Errorf("%s: expected estimate %v, got %v", c.name, c.estimate, tdee.Estimate)
+		}
+		if math.Abs(tdee.Upper-tdee.Estimate-c.margin) > 1e-6 || math.Abs(tdee.Estimate-tdee.Lower-c.margin) > 1e-6 {
+			t.Errorf("%s: expected bounds %v around estimate, got %v and %v", c.name, c.margin, tdee.Lower, tdee.Upper)
+		}
+		if len(tdee.MissingDays) != c.missing || tdee.LoggedDays != c.days-c.missing {
+			t.Errorf("%s: expected %d missing days, got %v", c.name, c.missing, tdee.MissingDays)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"database/sql"
+
+	"github.com/pkg/errors"
+)
+
+// DBTX is implemented by both *sql.DB and *sql.Tx, store functions accepting it can be used on their own
+// or as a part of a bigger transaction
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// WithTx runs fn in a transaction which is committed when fn succeeds and rolled back otherwise
+func WithTx(db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return errors.Wrap(err, "While starting transaction")
+	}
+	defer tx.Rollback()
+	err = fn(tx)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return errors.Wrap(err, "While commiting transaction")
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Base units portion amounts are stored in
+const (
+	BaseGram       = "g"
+	BaseMillilitre = "ml"
+)
+
+type unitFactor struct {
+	base   string
+	factor float64
+}
+
+// unitFactors maps unit names to their base unit and size in it
+var unitFactors = map[string]unitFactor{
+	"g":           {BaseGram, 1},
+	"gram":        {BaseGram, 1},
+	"grams":       {BaseGram, 1},
+	"kg":          {BaseGram, 1000},
+	"oz":          {BaseGram, 28.349523125},
+	"lb":          {BaseGram, 453.59237},
+	"ml":          {BaseMillilitre, 1},
+	"l":           {BaseMillilitre, 1000},
+	"cup":         {BaseMillilitre, 240},
+	"cups":        {BaseMillilitre, 240},
+	"tbsp":        {BaseMillilitre, 15},
+	"tablespoon":  {BaseMillilitre, 15},
+	"tablespoons": {BaseMillilitre, 15},
+	"tsp":         {BaseMillilitre, 5},
+	"teaspoon":    {BaseMillilitre, 5},
+	"teaspoons":   {BaseMillilitre, 5},
+}
+
+var unitPattern = regexp.MustCompile(`^(\d+(?:[.,]\d+)?)?\s*([a-z]+)\.?$`)
+
+// ParseUnit reads base amount of units like "100 g", "1 cup" or "l", returns false for units like "1 slice"
+func ParseUnit(unit string) (float64, string, bool) {
+	match := unitPattern.FindStringSubmatch(strings.ToLower(strings.TrimSpace(unit)))
+	if match == nil {
+		return 0, "", false
+	}
+	factor, ok := unitFactors[match[2]]
+	if !ok {
+		return 0, "", false
+	}
+	count := 1.0
+	if match[1] != "" {
+		parsed, err := strconv.ParseFloat(strings.Replace(match[1], ",", ".", 1), 64)
+		if err != nil || parsed <= 0 {
+			return 0, "", false
+		}
+		count = parsed
+	}
+	return count * factor.factor, factor.base, true
+}
+
+// ValidBaseUnit checks if base unit is one of the supported ones, empty base unit means portion can't be converted
+func ValidBaseUnit(base string) bool {
+	return base == "" || base == BaseGram || base == BaseMillilitre
+}
+
+// ConvertQuantity returns quantity of the target portion which has the same base amount as quantity of the source portion
+func ConvertQuantity(from Portion, quantity float64, to Portion) (float64, error) {
+	if from.BaseUnit == "" || from.Amount <= 0 {
+		return 0, errors.New("Source portion has no base amount")
+	}
+	return QuantityOfAmount(to, quantity*from.Amount, from.BaseUnit)
+}
+
+// QuantityOfAmount returns quantity of the portion which weighs (or measures) given amount of base unit
+func QuantityOfAmount(portion Portion, amount float64, base string) (float64, error) {
+	if portion.BaseUnit == "" || portion.Amount <= 0 {
+		return 0, errors.New("Portion has no base amount")
+	}
+	if portion.BaseUnit != base {
+		return 0, errors.New("Portions have different base units")
+	}
+	return amount / portion.Amount, nil
+}
+
+// PortionForAmount picks portion used to log an amount of base unit, the given portion or the first one measured in the base unit
+func PortionForAmount(portions []Portion, portionID int, base string) (*Portion, error) {
+	for _, portion := range portions {
+		if portionID != 0 && portion.ID != portionID {
+			continue
+		}
+		if portion.BaseUnit == base && portion.Amount > 0 {
+			return &portion, nil
+		}
+		if portionID != 0 {
+			return nil, errors.New("Portion has no base amount")
+		}
+	}
+	if portionID != 0 {
+		return nil, errors.New("Portion does not belong to the product")
+	}
+	return nil, errors.New("Product has no portion measured in " + base)
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParseUnit(t *testing.T) {
+	cases := []struct {
+		unit   string
+		amount float64
+		base   string
+		ok     bool
+	}{
+		{"100g", 100, BaseGram, true},
+		{"100 g", 100, BaseGram, true},
+		{" 1.5 KG ", 1500, BaseGram, true},
+		{"0,5 l", 500, BaseMillilitre, true},
+		{"l", 1000, BaseMillilitre, true},
+		{"2 cups", 480, BaseMillilitre, true},
+		{"1 tbsp.", 15, BaseMillilitre, true},
+		{"1 oz", 28.349523125, BaseGram, true},
+		{"1 slice", 0, "", false},
+		{"piece", 0, "", false},
+		{"0 g", 0, "", false},
+		{"-5 g", 0, "", false},
+		{"100 g of rice", 0, "", false},
+		{"", 0, "", false},
+	}
+	for _, c := range cases {
+		amount, base, ok := ParseUnit(c.unit)
+		if ok != c.ok || base != c.base || math.Abs(amount-c.amount) > 1e-9 {
+			t.Errorf("%q: expected %v %q %v, got %v %q %v", c.unit, c.amount, c.base, c.ok, amount, base, ok)
+		}
+	}
+}
+
+func TestConvertQuantity(t *testing.T) {
+	grams100 := Portion{Unit: "100g", Amount: 100, BaseUnit: BaseGram}
+	kilogram := Portion{Unit: "kg", Amount: 1000, BaseUnit: BaseGram}
+	cup := Portion{Unit: "cup", Amount: 240, BaseUnit: BaseMillilitre}
+	piece := Portion{Unit: "piece"}
+	cases := []struct {
+		name     string
+		from     Portion
+		quantity float64
+		to       Portion
+		want     float64
+		err      string
+	}{
+		{"grams to kilograms", grams100, 5, kilogram, 0.5, ""},
+		{"kilograms to grams", kilogram, 1.5, grams100, 15, ""},
+		{"same portion", cup, 2, cup, 2, ""},
+		{"zero quantity", grams100, 0, kilogram, 0, ""},
+		{"source without base amount", piece, 1, grams100, 0, "Source portion has no base amount"},
+		{"target without base amount", grams100, 1, piece, 0, "Portion has no base amount"},
+		{"different base units", grams100, 1, cup, 0, "Portions have different base units"},
+	}
+	for _, c := range cases {
+		quantity, err := ConvertQuantity(c.from, c.quantity, c.to)
+		if c.err != "" {
+			if err == nil || err.Error() != c.err {
+				t.Errorf("%s: expected error %q, got %v %v", c.name, c.err, quantity, err)
+			}
+			continue
+		}
+		if err != nil || math.Abs(quantity-c.want) > 1e-9 {
+			t.Errorf("%s: expected %v, got %v %v", c.name, c.want, quantity, err)
+		}
+	}
+}
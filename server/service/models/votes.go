@@ -6,65 +6,81 @@ import (
 	"github.com/pkg/errors"
 )
 
-type Rating struct {
-	UserID    int  `json:"userID"`
-	ProductID int  `json:"productID"`
-	Vote      Vote `json:"vote"`
-}
-
-func MigrateVotes(db *sql.DB) error {
-	rows, err := db.Query(`
-		CREATE TABLE IF NOT EXISTS votes (
-			user_id integer REFERENCES accounts(id),
-			product_id integer REFERENCES products(id),
-			vote integer NOT NULL,
-			PRIMARY KEY (user_id, product_id)
-		);
-	`)
-	if err != nil {
-		return errors.Wrap(err, "While creating votes table")
-	}
-	defer rows.Close()
-	return nil
-}
-
 type Vote int
 
 const (
-	UpVote   Vote = -1
-	DownVote Vote = 1
+	UpVote   Vote = 1
+	DownVote Vote = -1
 	None     Vote = 0
 )
 
+func (vote Vote) Valid() bool {
+	return vote == UpVote || vote == DownVote || vote == None
+}
+
+// VoteSummary struct is used to represent aggregated votes of a product together with the callers own vote.
+// Score is the lower bound of Wilson score interval of the up vote ratio, so few votes rank below many good ones.
+type VoteSummary struct {
+	Score     float64 `json:"score"`
+	Net       int     `json:"net"`
+	Count     int     `json:"count"`
+	UpVotes   int     `json:"upVotes"`
+	DownVotes int     `json:"downVotes"`
+	UserVote  Vote    `json:"userVote"`
+}
+
+// WilsonZ is the normal quantile of 95% confidence used for vote scores
+const WilsonZ = 1.96
+
+// RateProduct stores users vote, None withdraws it
 func RateProduct(db *sql.DB, userID, productID int, vote Vote) error {
+	if vote == None {
+		rows, err := db.Query(`
+			DELETE FROM votes WHERE user_id=$1 AND product_id=$2;
+		`, userID, productID)
+		if err != nil {
+			return errors.Wrap(err, "While withdrawing vote")
+		}
+		defer rows.Close()
+		return nil
+	}
 	rows, err := db.Query(`
 		INSERT INTO votes (user_id, product_id, vote)
 		VALUES ($1, $2, $3)
-		ON CONFLICT (user_id, product_id) DO UPDATE SET vote=$3
+		ON CONFLICT (user_id, product_id) DO UPDATE SET vote=$3, updated_at=now()
 	`, userID, productID, vote)
 	if err != nil {
-		return errors.Wrap(err, "While creating votes table")
+		return errors.Wrap(err, "While storing vote")
 	}
 	defer rows.Close()
 	return nil
 }
 
-func GetProductVotes(db *sql.DB, productID int) ([]Rating, error) {
-	rows, err := db.Query(`
-		SELECT * FROM votes WHERE product_id=$1
-	`, productID)
+func GetProductVoteSummary(db *sql.DB, productID, userID int) (*VoteSummary, error) {
+	summary := &VoteSummary{}
+	err := db.QueryRow(`
+		SELECT up, down, up + down, up - down, user_vote,
+			CASE WHEN up + down = 0 THEN 0 ELSE (
+				up::float / (up + down) + $3::float ^ 2 / (2 * (up + down))
+				- $3 * sqrt(up::float * down / (up + down) + $3::float ^ 2 / 4) / (up + down)
+			) / (1 + $3::float ^ 2 / (up + down)) END
+		FROM (
+			SELECT
+				COUNT(*) FILTER (WHERE vote = 1) AS up,
+				COUNT(*) FILTER (WHERE vote = -1) AS down,
+				COALESCE(MAX(vote) FILTER (WHERE user_id = $2), 0) AS user_vote
+			FROM votes WHERE product_id=$1
+		) AS counts;
+	`, productID, userID, WilsonZ).Scan(
+		&summary.UpVotes,
+		&summary.DownVotes,
+		&summary.Count,
+		&summary.Net,
+		&summary.UserVote,
+		&summary.Score,
+	)
 	if err != nil {
-		return nil, errors.Wrap(err, "While creating votes table")
-	}
-	defer rows.Close()
-	rates := []Rating{}
-	for rows.Next() {
-		rate := Rating{}
-		err := rows.Scan(&rate.UserID, &rate.ProductID, &rate.Vote)
-		if err != nil {
-			return nil, err
-		}
-		rates = append(rates, rate)
+		return nil, errors.Wrap(err, "While querying for votes")
 	}
-	return rates, nil
+	return summary, nil
 }
@@ -0,0 +1,224 @@
+package models
+
+import (
+	"database/sql"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+type WeightUnit string
+
+const (
+	Kilograms WeightUnit = "kg"
+	Pounds    WeightUnit = "lb"
+)
+
+const poundsPerKilogram = 2.20462262
+
+// Valid checks if unit is supported
+func (unit WeightUnit) Valid() bool {
+	return unit == Kilograms || unit == Pounds
+}
+
+// ToKilograms converts value given in unit to kilograms
+func (unit WeightUnit) ToKilograms(value float64) float64 {
+	if unit == Pounds {
+		return value / poundsPerKilogram
+	}
+	return value
+}
+
+// FromKilograms converts value given in kilograms to unit
+func (unit WeightUnit) FromKilograms(value float64) float64 {
+	if unit == Pounds {
+		return value * poundsPerKilogram
+	}
+	return value
+}
+
+// Weight struct is used to represent users body weight measurement
+type Weight struct {
+	ID     int        `json:"id"`
+	UserID int        `json:"userID"`
+	Date   time.Time  `json:"date"`
+	Weight float64    `json:"weight"`
+	Unit   WeightUnit `json:"unit"`
+}
+
+const weightColumns = `id, user_id, date, weight, unit`
+
+func (weight *Weight) scanRow(rows *sql.Rows) error {
+	err := rows.Scan(
+		&weight.ID,
+		&weight.UserID,
+		&weight.Date,
+		&weight.Weight,
+		&weight.Unit,
+	)
+	if err != nil {
+		return errors.Wrap(err, "While scaning row")
+	}
+	return nil
+}
+
+func CreateWeight(db *sql.DB, weight Weight) (*Weight, error) {
+	rows, err := db.Query(`
+		INSERT INTO weights (user_id, date, weight, unit)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+weightColumns+`;
+	`, weight.UserID, weight.Date, weight.Weight, weight.Unit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	weights := []Weight{}
+	for rows.Next() {
+		weight := Weight{}
+		err := weight.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		weights = append(weights, weight)
+	}
+	if len(weights) != 1 {
+		return nil, errors.New("Invalid return of insert operation")
+	}
+	return &weights[0], nil
+}
+
+func GetWeight(db *sql.DB, id int) (*Weight, error) {
+	rows, err := db.Query(`
+		SELECT `+weightColumns+` FROM weights WHERE id=$1;
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	weights := []Weight{}
+	for rows.Next() {
+		weight := Weight{}
+		err := weight.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		weights = append(weights, weight)
+	}
+	if len(weights) == 0 {
+		return nil, ErrNotFound
+	}
+	return &weights[0], nil
+}
+
+// GetUsersWeights returns users measurements between from and to (inclusive) ordered by date
+func GetUsersWeights(db *sql.DB, userID int, from, to time.Time) ([]Weight, error) {
+	rows, err := db.Query(`
+		SELECT `+weightColumns+` FROM weights WHERE user_id=$1 AND date BETWEEN $2 AND $3 ORDER BY date;
+	`, userID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	weights := []Weight{}
+	for rows.Next() {
+		weight := Weight{}
+		err := weight.scanRow(rows)
+		if err != nil {
+			return nil, err
+		}
+		weights = append(weights, weight)
+	}
+	return weights, nil
+}
+
+func UpdateWeight(db *sql.DB, id int, new Weight) error {
+	rows, err := db.Query(`
+		UPDATE weights SET date=$2, weight=$3, unit=$4 WHERE id=$1;
+	`, id, new.Date, new.Weight, new.Unit)
+	if err != nil {
+		return errors.Wrap(err, "While updating weight")
+	}
+	defer rows.Close()
+	return nil
+}
+
+func DeleteWeight(db *sql.DB, id int) error {
+	rows, err := db.Query(`
+		DELETE FROM weights WHERE id=$1;
+	`, id)
+	if err != nil {
+		return errors.Wrap(err, "While deleting weight")
+	}
+	defer rows.Close()
+	return nil
+}
+
+// TrendSmoothing is the weight given to a new measurement in the exponentially smoothed trend
+const TrendSmoothing = 0.1
+
+// TrendPoint struct is used to represent measured and smoothed weight on a day, both in kilograms
+type TrendPoint struct {
+	Date   time.Time `json:"date"`
+	Weight float64   `json:"weight"`
+	Trend  float64   `json:"trend"`
+}
+
+// WeightTrend computes exponentially smoothed trend of measurements.
+// Days without measurement decay the smoothing factor, so a measurement after a gap moves the trend further.
+func WeightTrend(weights []Weight) []TrendPoint {
+	sorted := make([]Weight, len(weights))
+	copy(sorted, weights)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Date.Before(sorted[j].Date)
+	})
+	points := []TrendPoint{}
+	for _, weight := range sorted {
+		kg := weight.Unit.ToKilograms(weight.Weight)
+		point := TrendPoint{Date: Day(weight.Date), Weight: kg, Trend: kg}
+		if len(points) > 0 {
+			prev := points[len(points)-1]
+			days := point.Date.Sub(prev.Date).Hours() / 24
+			if days < 1 {
+				days = 1
+			}
+			keep := math.Pow(1-TrendSmoothing, days)
+			point.Trend = prev.Trend + (1-keep)*(kg-prev.Trend)
+		}
+		points = append(points, point)
+	}
+	return points
+}
+
+// WeeklyRate returns change of trend per week in kilograms, fitted over the last two weeks of points
+func WeeklyRate(points []TrendPoint) float64 {
+	if len(points) < 2 {
+		return 0
+	}
+	last := points[len(points)-1].Date
+	window := []TrendPoint{}
+	for _, point := range points {
+		if last.Sub(point.Date) <= 14*24*time.Hour {
+			window = append(window, point)
+		}
+	}
+	if len(window) < 2 {
+		window = points[len(points)-2:]
+	}
+	var sumX, sumY, sumXY, sumXX float64
+	for _, point := range window {
+		x := point.Date.Sub(last).Hours() / 24
+		sumX += x
+		sumY += point.Trend
+		sumXY += x * point.Trend
+		sumXX += x * x
+	}
+	n := float64(len(window))
+	denominator := n*sumXX - sumX*sumX
+	if denominator == 0 {
+		return 0
+	}
+	slope := (n*sumXY - sumX*sumY) / denominator
+	return slope * 7
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestWeightTrend(t *testing.T) {
+	date := func(day int) time.Time {
+		return time.Date(2024, time.March, day, 0, 0, 0, 0, time.UTC)
+	}
+	cases := []struct {
+		name    string
+		weights []Weight
+		trend   []float64
+	}{
+		{"no measurements", nil, []float64{}},
+		{"single measurement", []Weight{{Date: date(1), Weight: 80, Unit: Kilograms}}, []float64{80}},
+		{
+			"consecutive days",
+			[]Weight{{Date: date(1), Weight: 80, Unit: Kilograms}, {Date: date(2), Weight: 90, Unit: Kilograms}},
+			[]float64{80, 81},
+		},
+		{
+			"gap decays smoothing",
+			[]Weight{{Date: date(1), Weight: 80, Unit: Kilograms}, {Date: date(3), Weight: 90, Unit: Kilograms}},
+			[]float64{80, 81.9},
+		},
+		{
+			"unsorted measurements",
+			[]Weight{{Date: date(2), Weight: 90, Unit: Kilograms}, {Date: date(1), Weight: 80, Unit: Kilograms}},
+			[]float64{80, 81},
+		},
+		{
+			"pounds converted to kilograms",
+			[]Weight{{Date: date(1), Weight: 80, Unit: Kilograms}, {Date: date(2), Weight: 90 * poundsPerKilogram, Unit: Pounds}},
+			[]float64{80, 81},
+		},
+		{
+			"same day counts as one day",
+			[]Weight{{Date: date(1), Weight: 80, Unit: Kilograms}, {Date: date(1).Add(12 * time.Hour), Weight: 90, Unit: Kilograms}},
+			[]float64{80, 81},
+		},
+	}
+	for _, c := range cases {
+		points := WeightTrend(c.weights)
+		if len(points) != len(c.trend) {
+			t.Errorf("%s: expected %d points, got %d", c.name, len(c.trend), len(points))
+			continue
+		}
+		for i, point := range points {
+			if math.Abs(point.Trend-c.trend[i]) > 1e-9 {
+				t.Errorf("%s: expected trend %v at point %d, got %v", c.name, c.trend[i], i, point.Trend)
+			}
+		}
+	}
+}
+
+func TestWeeklyRate(t *testing.T) {
+	line := func(days int, perDay float64) []TrendPoint {
+		points := []TrendPoint{}
+		for day := 0; day < days; day++ {
+			date := time.Date(2024, time.March, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, day)
+			points = append(points, TrendPoint{Date: date, Trend: 80 + perDay*float64(day)})
+		}
+		return points
+	}
+	cases := []struct {
+		name   string
+		points []TrendPoint
+		rate   float64
+	}{
+		{"no points", nil, 0},
+		{"single point", line(1, 0.1), 0},
+		{"losing weight", line(10, -0.1), -0.7},
+		{"stable weight", line(10, 0), 0},
+		{"only last two weeks count", append(line(30, 0.5)[:10], line(30, 0.2)[10:]...), 1.4},
+	}
+	for _, c := range cases {
+		rate := WeeklyRate(c.points)
+		if math.Abs(rate-c.rate) > 1e-9 {
+			t.Errorf("%s: expected rate %v, got %v", c.name, c.rate, rate)
+		}
+	}
+}
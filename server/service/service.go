@@ -3,6 +3,7 @@ package service
 import (
 	"app/service/handlers"
 	"app/service/middleware"
+	"app/service/store"
 
 	"app/service/auth"
 
@@ -22,56 +23,135 @@ func NewService() error {
 		return errors.Wrap(err, "While connecting to db")
 	}
 	defer db.Close()
+	stores := store.NewPostgres(db)
 	router := mux.NewRouter()
 
 	router.Use(middleware.WithCors)
 	router.Use(middleware.WithTracing)
 
 	router.Handle("/api/user/new", middleware.WithAuth(
-		handlers.CreateAccount(db, logger), db, auth.Default))
+		handlers.CreateAccount(stores, logger), stores.Accounts, auth.Default))
 	router.Handle("/api/user/login", middleware.WithAuth(
-		handlers.Authenticate(db, logger), db, auth.Default))
-	router.Handle("/api/user/check-token", handlers.CheckIfAuthenticated(db, logger))
-	router.Handle("/api/user/verify", handlers.Verify(db, logger))
-	router.Handle("/api/user/remind-password", handlers.MailChangePasswordLink(db, logger))
-	router.Handle("/api/user/change-password", handlers.ChangePassword(db, logger))
+		handlers.Authenticate(stores, logger), stores.Accounts, auth.Default))
+	router.Handle("/api/user/check-token", handlers.CheckIfAuthenticated(stores, logger))
+	router.Handle("/api/user/verify", handlers.Verify(stores, logger))
+	router.Handle("/api/user/remind-password", handlers.MailChangePasswordLink(stores, logger))
+	router.Handle("/api/user/change-password", handlers.ChangePassword(stores, logger))
 	router.Handle("/api/user/ban", middleware.WithAuth(
-		handlers.BanUser(db, logger), db, auth.Moderator))
+		handlers.BanUser(stores, logger), stores.Accounts, auth.Moderator))
 	router.Handle("/api/user/unban", middleware.WithAuth(
-		handlers.UnbanUser(db, logger), db, auth.Moderator))
+		handlers.UnbanUser(stores, logger), stores.Accounts, auth.Moderator))
 
 	router.Handle("/api/user/search", middleware.WithAuth(
-		handlers.SearchUsers(db, logger), db, auth.Moderator))
+		handlers.SearchUsers(stores, logger), stores.Accounts, auth.Moderator))
 	router.Handle("/api/user/products", middleware.WithAuth(
-		handlers.GetUsersAddedProducts(db, logger), db, auth.Moderator))
+		handlers.GetUsersAddedProducts(stores, logger), stores.Accounts, auth.Moderator))
 
 	router.Handle("/api/user/priviledges", middleware.WithAuth(
-		handlers.SetAccessLevel(db, logger), db, auth.Admin))
+		handlers.SetAccessLevel(stores, logger), stores.Accounts, auth.Admin))
 
 	router.Handle("/api/user/entries/create", middleware.WithAuth(
-		handlers.CreateEntry(db, logger), db, auth.User))
+		handlers.CreateEntry(stores, logger), stores.Accounts, auth.User))
 	router.Handle("/api/user/entries/view", middleware.WithAuth(
-		handlers.GetUsersEntries(db, logger), db, auth.User))
+		handlers.GetUsersEntries(stores, logger), stores.Accounts, auth.User))
 	router.Handle("/api/user/entries/delete", middleware.WithAuth(
-		handlers.DeleteEntry(db, logger), db, auth.User))
+		handlers.DeleteEntry(stores, logger), stores.Accounts, auth.User))
 	router.Handle("/api/user/entries/update", middleware.WithAuth(
-		handlers.UpdateEntry(db, logger), db, auth.User))
+		handlers.UpdateEntry(stores, logger), stores.Accounts, auth.User))
 	router.Handle("/api/user/entries/dates", middleware.WithAuth(
-		handlers.GetUsersDatesWithEntries(db, logger), db, auth.User))
+		handlers.GetUsersDatesWithEntries(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/entries/copy", middleware.WithAuth(
+		handlers.CopyEntries(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/entries/summary", middleware.WithAuth(
+		handlers.GetUsersSummary(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/entries/frequent", middleware.WithAuth(
+		handlers.GetFrequentProducts(stores, logger), stores.Accounts, auth.User))
+
+	router.Handle("/api/user/meals/create", middleware.WithAuth(
+		handlers.CreateSavedMeal(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/meals/view", middleware.WithAuth(
+		handlers.GetSavedMeals(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/meals/delete", middleware.WithAuth(
+		handlers.DeleteSavedMeal(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/meals/log", middleware.WithAuth(
+		handlers.LogSavedMeal(stores, logger), stores.Accounts, auth.User))
+
+	router.Handle("/api/user/favourites/add", middleware.WithAuth(
+		handlers.AddFavourite(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/favourites/remove", middleware.WithAuth(
+		handlers.RemoveFavourite(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/favourites/view", middleware.WithAuth(
+		handlers.GetFavourites(stores, logger), stores.Accounts, auth.User))
+
+	router.Handle("/api/user/slots/view", middleware.WithAuth(
+		handlers.GetSlots(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/slots/set", middleware.WithAuth(
+		handlers.SetSlots(stores, logger), stores.Accounts, auth.User))
+
+	router.Handle("/api/user/weights/create", middleware.WithAuth(
+		handlers.CreateWeight(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/weights/view", middleware.WithAuth(
+		handlers.GetWeights(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/weights/update", middleware.WithAuth(
+		handlers.UpdateWeight(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/weights/delete", middleware.WithAuth(
+		handlers.DeleteWeight(stores, logger), stores.Accounts, auth.User))
+
+	router.Handle("/api/user/tdee", middleware.WithAuth(
+		handlers.GetTDEE(stores, logger), stores.Accounts, auth.User))
+
+	router.Handle("/api/user/goals/set", middleware.WithAuth(
+		handlers.SetGoal(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/goals/view", middleware.WithAuth(
+		handlers.GetGoals(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/user/goals/delete", middleware.WithAuth(
+		handlers.DeleteGoal(stores, logger), stores.Accounts, auth.User))
 
 	router.Handle("/api/product/new", middleware.WithAuth(
-		handlers.CreateProduct(db, logger), db, auth.User))
+		handlers.CreateProduct(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/product/recipe/new", middleware.WithAuth(
+		handlers.CreateRecipe(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/product/recipe/view", middleware.WithAuth(
+		handlers.GetRecipe(stores, logger), stores.Accounts, auth.User))
 	router.Handle("/api/product/view", middleware.WithAuth(
-		handlers.GetProduct(db, logger), db, auth.User))
+		handlers.GetProduct(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/product/barcode", middleware.WithAuth(
+		handlers.GetProductByBarcode(stores, logger), stores.Accounts, auth.User))
 	router.Handle("/api/product/search", middleware.WithAuth(
-		handlers.SearchProduct(db, logger), db, auth.User))
+		handlers.SearchProduct(stores, logger), stores.Accounts, auth.User))
 	router.Handle("/api/product/rate", middleware.WithAuth(
-		handlers.RateProduct(db, logger), db, auth.User))
+		handlers.RateProduct(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/product/proposals/new", middleware.WithAuth(
+		handlers.ProposeProductChange(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/product/proposals/view", middleware.WithAuth(
+		handlers.GetProposals(stores, logger), stores.Accounts, auth.Moderator))
+	router.Handle("/api/product/proposals/approve", middleware.WithAuth(
+		handlers.ApproveProposal(stores, logger), stores.Accounts, auth.Moderator))
+	router.Handle("/api/product/proposals/reject", middleware.WithAuth(
+		handlers.RejectProposal(stores, logger), stores.Accounts, auth.Moderator))
 
 		router.Handle("/api/product/delete", middleware.WithAuth(
-		handlers.DeleteProduct(db, logger), db, auth.Moderator))
+		handlers.DeleteProduct(stores, logger), stores.Accounts, auth.Moderator))
+	router.Handle("/api/product/restore", middleware.WithAuth(
+		handlers.RestoreProduct(stores, logger), stores.Accounts, auth.Moderator))
 	router.Handle("/api/product/update", middleware.WithAuth(
-		handlers.UpdateProduct(db, logger), db, auth.Moderator))
+		handlers.UpdateProduct(stores, logger), stores.Accounts, auth.Moderator))
+	router.Handle("/api/product/portions/add", middleware.WithAuth(
+		handlers.AddPortion(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/product/portions/update", middleware.WithAuth(
+		handlers.UpdatePortion(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/product/portions/remove", middleware.WithAuth(
+		handlers.RemovePortion(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/product/portions/convert", middleware.WithAuth(
+		handlers.ConvertPortion(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/product/duplicates", middleware.WithAuth(
+		handlers.FindDuplicates(stores, logger), stores.Accounts, auth.Moderator))
+	router.Handle("/api/product/merge", middleware.WithAuth(
+		handlers.MergeProducts(stores, logger), stores.Accounts, auth.Moderator))
+	router.Handle("/api/product/revisions/view", middleware.WithAuth(
+		handlers.GetRevisions(stores, logger), stores.Accounts, auth.User))
+	router.Handle("/api/product/revisions/rollback", middleware.WithAuth(
+		handlers.RollbackProduct(stores, logger), stores.Accounts, auth.Moderator))
 
 	router.PathPrefix("/").Handler(http.FileServer(http.Dir("./static/")))
 
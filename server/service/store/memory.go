@@ -0,0 +1,1450 @@
+package store
+
+import (
+	"app/service/auth"
+	"app/service/models"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/pkg/errors"
+)
+
+// uniqueViolation mimics postgres error handlers check for duplicate emails and barcodes
+func uniqueViolation(constraint string) error {
+	return &pq.Error{Code: "23505", Constraint: constraint}
+}
+
+// memory implements every store with maps, it is meant for tests.
+// Revisions are not kept and there is no trigram search, revisions and duplicates stores return ErrNotSupported.
+type memory struct {
+	mu          sync.Mutex
+	nextID      int
+	accounts    map[int]models.Account
+	products    map[int]models.Product
+	barcodes    map[string]int
+	portions    map[int]models.Portion
+	entries     map[int]models.Entry
+	votes       map[[2]int]models.Vote
+	ingredients map[int]models.Ingredient
+	savedMeals  map[int]models.SavedMeal
+	favourites  map[[2]int]time.Time
+	slots       map[int][]string
+	weights     map[int]models.Weight
+	goals       map[int]models.Goal
+	proposals   map[int]models.Proposal
+}
+
+// NewMemory returns empty stores kept in memory
+func NewMemory() *Stores {
+	m := &memory{
+		accounts:    map[int]models.Account{},
+		products:    map[int]models.Product{},
+		barcodes:    map[string]int{},
+		portions:    map[int]models.Portion{},
+		entries:     map[int]models.Entry{},
+		votes:       map[[2]int]models.Vote{},
+		ingredients: map[int]models.Ingredient{},
+		savedMeals:  map[int]models.SavedMeal{},
+		favourites:  map[[2]int]time.Time{},
+		slots:       map[int][]string{},
+		weights:     map[int]models.Weight{},
+		goals:       map[int]models.Goal{},
+		proposals:   map[int]models.Proposal{},
+	}
+	return &Stores{
+		Accounts:   m,
+		Products:   m,
+		Portions:   m,
+		Entries:    m,
+		Votes:      m,
+		Recipes:    m,
+		SavedMeals: m,
+		Favourites: m,
+		Weights:    m,
+		Goals:      m,
+		Proposals:  m,
+		Duplicates: m,
+		Revisions:  m,
+	}
+}
+
+// id returns next identifier, identifiers are unique across all kinds of records
+func (m *memory) id() int {
+	m.nextID++
+	return m.nextID
+}
+
+func (m *memory) CreateAccount(acc *models.Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	email := strings.ToLower(acc.Email)
+	for _, existing := range m.accounts {
+		if existing.Email == email {
+			return uniqueViolation("accounts_email_key")
+		}
+	}
+	created := models.Account{ID: m.id(), Email: email, Password: acc.Password, AccessLevel: acc.AccessLevel}
+	m.accounts[created.ID] = created
+	return nil
+}
+
+func (m *memory) VerifyAccount(acc *models.Account) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.accounts[acc.ID]
+	if ok && existing.Email == acc.Email && existing.AccessLevel == acc.AccessLevel {
+		existing.Verified = true
+		m.accounts[acc.ID] = existing
+	}
+	return nil
+}
+
+func (m *memory) GetAccountById(id int) (*models.Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	acc, ok := m.accounts[id]
+	if !ok {
+		return nil, errors.New("Account not found")
+	}
+	return &acc, nil
+}
+
+func (m *memory) GetAccountByEmail(email string) (*models.Account, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	email = strings.ToLower(email)
+	for _, acc := range m.accounts {
+		if acc.Email == email {
+			return &acc, nil
+		}
+	}
+	return nil, errors.New("Account not found")
+}
+
+func (m *memory) GetAccountsCount() (int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.accounts), nil
+}
+
+func (m *memory) SetAccessLevel(id int, accessLevel auth.AccessLevel) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if acc, ok := m.accounts[id]; ok {
+		acc.AccessLevel = accessLevel
+		m.accounts[id] = acc
+	}
+	return nil
+}
+
+func (m *memory) SearchAccounts(email string, pagination models.Pagination) (*[]models.Account, *models.Pagination, error) {
+	var lastEmail string
+	var lastID int
+	keyset, err := pagination.CursorKeys(&lastEmail, &lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	pattern := strings.ToLower(email)
+	matched := []models.Account{}
+	for _, acc := range m.accounts {
+		if strings.Contains(acc.Email, pattern) {
+			matched = append(matched, acc)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Email != matched[j].Email {
+			return matched[i].Email < matched[j].Email
+		}
+		return matched[i].ID < matched[j].ID
+	})
+	start := pagination.Offset()
+	if keyset {
+		start = sort.Search(len(matched), func(i int) bool {
+			return matched[i].Email > lastEmail || (matched[i].Email == lastEmail && matched[i].ID > lastID)
+		})
+	}
+	bounds, more := page(len(matched), start, pagination.Limit())
+	out := append([]models.Account{}, matched[bounds[0]:bounds[1]]...)
+	if !more {
+		return &out, pagination.Result(len(matched), false), nil
+	}
+	last := out[len(out)-1]
+	return &out, pagination.Result(len(matched), true, last.Email, last.ID), nil
+}
+
+// page returns bounds of the page starting at start and tells if items follow it
+func page(count, start, limit int) ([2]int, bool) {
+	if start > count {
+		start = count
+	}
+	end := start + limit
+	if end >= count {
+		return [2]int{start, count}, false
+	}
+	return [2]int{start, end}, true
+}
+
+func (m *memory) ChangePasswordRequest(email string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, acc := range m.accounts {
+		if acc.Email == email {
+			acc.ChangePassword = true
+			m.accounts[id] = acc
+		}
+	}
+	return nil
+}
+
+func (m *memory) ChangePassword(email, password string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, acc := range m.accounts {
+		if acc.Email == email {
+			acc.Password = password
+			acc.ChangePassword = false
+			m.accounts[id] = acc
+		}
+	}
+	return nil
+}
+
+func (m *memory) CreateProductWithPortions(product models.Product, portions []models.Portion, barcodes []string) (*models.Product, []models.Portion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name, err := m.claimProductName(product.Name, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, barcode := range barcodes {
+		if _, ok := m.barcodes[barcode]; ok {
+			return nil, nil, uniqueViolation("barcodes_pkey")
+		}
+	}
+	created := models.Product{
+		ID:          m.id(),
+		Creator:     product.Creator,
+		Name:        name,
+		Description: product.Description,
+		Recipe:      product.Recipe,
+		Yield:       product.Yield,
+	}
+	m.products[created.ID] = created
+	createdPortions := []models.Portion{}
+	for _, portion := range portions {
+		portion = portion.WithBase()
+		portion.ID = m.id()
+		portion.ProductID = created.ID
+		m.portions[portion.ID] = portion
+		createdPortions = append(createdPortions, portion)
+	}
+	for _, barcode := range barcodes {
+		m.barcodes[barcode] = created.ID
+	}
+	return &created, createdPortions, nil
+}
+
+func (m *memory) GetProductById(id int) (*models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product, ok := m.products[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &product, nil
+}
+
+func (m *memory) GetProductByBarcode(code string) (*models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product, ok := m.products[m.barcodes[code]]
+	if !ok || product.Deleted {
+		return nil, models.ErrNotFound
+	}
+	return &product, nil
+}
+
+func (m *memory) GetProductsBarcodes(productID int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	codes := []string{}
+	for code, id := range m.barcodes {
+		if id == productID {
+			codes = append(codes, code)
+		}
+	}
+	sort.Strings(codes)
+	return codes, nil
+}
+
+func (m *memory) BarcodeExists(code string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.barcodes[code]
+	return ok, nil
+}
+
+// GetProductsByName matches products whose name contains the term, shorter names rank higher
+func (m *memory) GetProductsByName(name string, userID int, favouritesOnly bool, pagination models.Pagination) (*[]models.ScoredProduct, *models.Pagination, error) {
+	var lastScore float64
+	var lastName string
+	var lastID int
+	keyset, err := pagination.CursorKeys(&lastScore, &lastName, &lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	term := strings.ToLower(strings.TrimSpace(name))
+	matched := []models.ScoredProduct{}
+	for _, product := range m.products {
+		_, favourite := m.favourites[[2]int{userID, product.ID}]
+		if product.Deleted || (favouritesOnly && !favourite) || !strings.Contains(product.Name, term) {
+			continue
+		}
+		score := 0.0
+		if term != "" {
+			score = math.Round(float64(len(term))/float64(len(product.Name))*1e6) / 1e6
+		}
+		matched = append(matched, models.ScoredProduct{Product: product, Score: score, Favourite: favourite})
+	}
+	after := func(a models.ScoredProduct, score float64, name string, id int) bool {
+		if a.Score != score {
+			return a.Score < score
+		}
+		if a.Name != name {
+			return a.Name > name
+		}
+		return a.ID > id
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return after(matched[j], matched[i].Score, matched[i].Name, matched[i].ID)
+	})
+	start := pagination.Offset()
+	if keyset {
+		start = sort.Search(len(matched), func(i int) bool {
+			return after(matched[i], lastScore, lastName, lastID)
+		})
+	}
+	bounds, more := page(len(matched), start, pagination.Limit())
+	out := append([]models.ScoredProduct{}, matched[bounds[0]:bounds[1]]...)
+	if !more {
+		return &out, pagination.Result(len(matched), false), nil
+	}
+	last := out[len(out)-1]
+	return &out, pagination.Result(len(matched), true, last.Score, last.Name, last.ID), nil
+}
+
+func (m *memory) GetProductsByCreatorID(id int, pagination models.Pagination) (*[]models.Product, *models.Pagination, error) {
+	var lastName string
+	var lastID int
+	keyset, err := pagination.CursorKeys(&lastName, &lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	matched := []models.Product{}
+	for _, product := range m.products {
+		if product.Creator == id {
+			matched = append(matched, product)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].Name != matched[j].Name {
+			return matched[i].Name < matched[j].Name
+		}
+		return matched[i].ID < matched[j].ID
+	})
+	start := pagination.Offset()
+	if keyset {
+		start = sort.Search(len(matched), func(i int) bool {
+			return matched[i].Name > lastName || (matched[i].Name == lastName && matched[i].ID > lastID)
+		})
+	}
+	bounds, more := page(len(matched), start, pagination.Limit())
+	out := append([]models.Product{}, matched[bounds[0]:bounds[1]]...)
+	if !more {
+		return &out, pagination.Result(len(matched), false), nil
+	}
+	last := out[len(out)-1]
+	return &out, pagination.Result(len(matched), true, last.Name, last.ID), nil
+}
+
+func (m *memory) ProductNameExists(name string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, product := range m.products {
+		if product.Name == name {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (m *memory) UpdateProduct(id int, new models.Product, userID int) (*models.Product, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product, ok := m.products[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	if product.Deleted {
+		return nil, models.ErrProductDeleted
+	}
+	name, err := m.claimProductName(new.Name, id)
+	if err != nil {
+		return nil, err
+	}
+	product.Name = name
+	m.products[id] = product
+	return &product, nil
+}
+
+// claimProductName normalizes the name and checks that no other product than exceptID has it
+func (m *memory) claimProductName(name string, exceptID int) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return "", models.ErrEmptyProductName
+	}
+	for _, existing := range m.products {
+		if existing.Name == name && existing.ID != exceptID {
+			return "", models.ErrProductExists
+		}
+	}
+	return name, nil
+}
+
+func (m *memory) DeleteProduct(id, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product, ok := m.products[id]
+	if !ok || product.Deleted {
+		return models.ErrNotFound
+	}
+	now := time.Now()
+	product.Deleted = true
+	product.DeletedBy = userID
+	product.DeletedAt = &now
+	m.products[id] = product
+	return nil
+}
+
+func (m *memory) RestoreProduct(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product, ok := m.products[id]
+	if !ok || !product.Deleted {
+		return models.ErrNotFound
+	}
+	product.Deleted = false
+	product.DeletedBy = 0
+	product.DeletedAt = nil
+	m.products[id] = product
+	return nil
+}
+
+func (m *memory) GetPortion(id int) (*models.Portion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	portion, ok := m.portions[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &portion, nil
+}
+
+func (m *memory) GetProductsPortions(productID int) ([]models.Portion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.productsPortions(productID), nil
+}
+
+func (m *memory) productsPortions(productID int) []models.Portion {
+	portions := []models.Portion{}
+	for _, portion := range m.portions {
+		if portion.ProductID == productID {
+			portions = append(portions, portion)
+		}
+	}
+	sort.Slice(portions, func(i, j int) bool {
+		return portions[i].ID < portions[j].ID
+	})
+	return portions
+}
+
+func (m *memory) AddProductPortion(portion models.Portion, userID int) (*models.Portion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.products[portion.ProductID]; !ok {
+		return nil, models.ErrNotFound
+	}
+	portion = portion.WithBase()
+	portion.ID = m.id()
+	m.portions[portion.ID] = portion
+	return &portion, nil
+}
+
+func (m *memory) EditProductPortion(portion models.Portion, userID int) (*models.Portion, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	existing, ok := m.portions[portion.ID]
+	if !ok || existing.ProductID != portion.ProductID {
+		return nil, models.ErrNotFound
+	}
+	portion = portion.WithBase()
+	m.portions[portion.ID] = portion
+	m.refreshRecipesUsingProduct(portion.ProductID, map[int]bool{})
+	return &portion, nil
+}
+
+func (m *memory) RemoveProductPortion(productID, portionID, userID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.products[productID]; !ok {
+		return models.ErrNotFound
+	}
+	if len(m.productsPortions(productID)) < 2 {
+		return errors.New("Product has to keep at least one portion")
+	}
+	for _, entry := range m.entries {
+		if entry.PortionID == portionID {
+			return errors.New("Portion is in use")
+		}
+	}
+	for _, ingredient := range m.ingredients {
+		if ingredient.PortionID == portionID {
+			return errors.New("Portion is in use")
+		}
+	}
+	for _, meal := range m.savedMeals {
+		for _, item := range meal.Items {
+			if item.PortionID == portionID {
+				return errors.New("Portion is in use")
+			}
+		}
+	}
+	portion, ok := m.portions[portionID]
+	if !ok || portion.ProductID != productID {
+		return models.ErrNotFound
+	}
+	delete(m.portions, portionID)
+	return nil
+}
+
+func (m *memory) CreateEntry(entry *models.Entry) (*models.Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	created := *entry
+	created.ID = m.id()
+	created.Date = models.Day(created.Date)
+	m.entries[created.ID] = created
+	return &created, nil
+}
+
+func (m *memory) GetEntry(id int) (*models.Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &entry, nil
+}
+
+func (m *memory) UpdateEntry(id int, new *models.Entry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	entry, ok := m.entries[id]
+	if !ok {
+		return nil
+	}
+	entry.ProductID = new.ProductID
+	entry.PortionID = new.PortionID
+	entry.Quantity = new.Quantity
+	entry.Date = models.Day(new.Date)
+	entry.Slot = new.Slot
+	m.entries[id] = entry
+	return nil
+}
+
+func (m *memory) DeleteEntry(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, id)
+	return nil
+}
+
+func (m *memory) GetUsersSlots(userID int) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.usersSlots(userID), nil
+}
+
+func (m *memory) usersSlots(userID int) []string {
+	slots, ok := m.slots[userID]
+	if !ok {
+		slots = models.DefaultSlots
+	}
+	return append([]string{}, slots...)
+}
+
+func (m *memory) SetUsersSlots(userID int, slots []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.slots[userID] = append([]string{}, slots...)
+	return nil
+}
+
+func (m *memory) RateProduct(userID, productID int, vote models.Vote) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := [2]int{userID, productID}
+	if vote == models.None {
+		delete(m.votes, key)
+		return nil
+	}
+	m.votes[key] = vote
+	return nil
+}
+
+// wilsonScore mirrors the score models.GetProductVoteSummary computes in SQL, the lower bound of Wilson score
+// interval of the up vote ratio, zero when there are no votes
+func wilsonScore(up, down int) float64 {
+	if up+down == 0 {
+		return 0
+	}
+	n := float64(up + down)
+	z := models.WilsonZ
+	return (float64(up)/n + z*z/(2*n) - z*math.Sqrt(float64(up)*float64(down)/n+z*z/4)/n) / (1 + z*z/n)
+}
+
+func (m *memory) GetProductVoteSummary(productID, userID int) (*models.VoteSummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	summary := &models.VoteSummary{}
+	for key, vote := range m.votes {
+		if key[1] != productID {
+			continue
+		}
+		if vote == models.UpVote {
+			summary.UpVotes++
+		} else {
+			summary.DownVotes++
+		}
+		if key[0] == userID {
+			summary.UserVote = vote
+		}
+	}
+	summary.Count = summary.UpVotes + summary.DownVotes
+	summary.Net = summary.UpVotes - summary.DownVotes
+	summary.Score = wilsonScore(summary.UpVotes, summary.DownVotes)
+	return summary, nil
+}
+
+func (m *memory) CreateRecipe(recipe models.Product, ingredients []models.Ingredient) (*models.Product, []models.Ingredient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name, err := m.claimProductName(recipe.Name, 0)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, ingredient := range ingredients {
+		if product, ok := m.products[ingredient.ProductID]; !ok || product.Deleted {
+			return nil, nil, models.ErrDeletedIngredient
+		}
+		if _, ok := m.portions[ingredient.PortionID]; !ok {
+			return nil, nil, errors.New("Ingredient portion does not exist")
+		}
+	}
+	created := models.Product{
+		ID:          m.id(),
+		Creator:     recipe.Creator,
+		Name:        name,
+		Description: recipe.Description,
+		Recipe:      true,
+		Yield:       recipe.Yield,
+	}
+	m.products[created.ID] = created
+	createdIngredients := []models.Ingredient{}
+	for _, ingredient := range ingredients {
+		ingredient.ID = m.id()
+		ingredient.RecipeID = created.ID
+		m.ingredients[ingredient.ID] = ingredient
+		createdIngredients = append(createdIngredients, ingredient)
+	}
+	m.refreshRecipe(created.ID, map[int]bool{})
+	return &created, createdIngredients, nil
+}
+
+func (m *memory) GetRecipesIngredients(recipeID int) ([]models.Ingredient, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.recipesIngredients(recipeID), nil
+}
+
+func (m *memory) recipesIngredients(recipeID int) []models.Ingredient {
+	ingredients := []models.Ingredient{}
+	for _, ingredient := range m.ingredients {
+		if ingredient.RecipeID == recipeID {
+			ingredients = append(ingredients, ingredient)
+		}
+	}
+	sort.Slice(ingredients, func(i, j int) bool {
+		return ingredients[i].ID < ingredients[j].ID
+	})
+	return ingredients
+}
+
+// refreshRecipe recomputes portions of the recipe and recipes using it the same way models.RefreshRecipe does
+func (m *memory) refreshRecipe(recipeID int, visited map[int]bool) {
+	if visited[recipeID] {
+		return
+	}
+	visited[recipeID] = true
+	recipe, ok := m.products[recipeID]
+	if !ok || !recipe.Recipe {
+		return
+	}
+	ingredients := m.recipesIngredients(recipeID)
+	ingredientPortions := []models.Portion{}
+	for _, ingredient := range ingredients {
+		ingredientPortions = append(ingredientPortions, m.portions[ingredient.PortionID])
+	}
+	derived := models.DeriveRecipePortions(recipe.Yield, ingredients, ingredientPortions)
+	for _, portion := range m.productsPortions(recipeID) {
+		derivedPortion, ok := derived[portion.Unit]
+		if !ok {
+			continue
+		}
+		portion.Nutrition = derivedPortion.Nutrition
+		portion.Amount = derivedPortion.Amount
+		portion.BaseUnit = derivedPortion.BaseUnit
+		m.portions[portion.ID] = portion
+		delete(derived, portion.Unit)
+	}
+	for _, unit := range []string{models.RecipeServingUnit, models.RecipeWholeUnit} {
+		portion, ok := derived[unit]
+		if !ok {
+			continue
+		}
+		portion.ID = m.id()
+		portion.ProductID = recipeID
+		m.portions[portion.ID] = portion
+	}
+	m.refreshRecipesUsingProduct(recipeID, visited)
+}
+
+func (m *memory) refreshRecipesUsingProduct(productID int, visited map[int]bool) {
+	for _, ingredient := range m.ingredients {
+		if ingredient.ProductID == productID {
+			m.refreshRecipe(ingredient.RecipeID, visited)
+		}
+	}
+}
+
+func (m *memory) CreateSavedMeal(meal models.SavedMeal) (*models.SavedMeal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	name := strings.TrimSpace(meal.Name)
+	for _, existing := range m.savedMeals {
+		if existing.UserID == meal.UserID && existing.Name == name {
+			return nil, uniqueViolation("saved_meals_user_id_name_key")
+		}
+	}
+	created := models.SavedMeal{ID: m.id(), UserID: meal.UserID, Name: name, Items: []models.SavedMealItem{}}
+	for _, item := range meal.Items {
+		item.ID = m.id()
+		item.MealID = created.ID
+		created.Items = append(created.Items, item)
+	}
+	m.savedMeals[created.ID] = created
+	return copySavedMeal(created), nil
+}
+
+// copySavedMeal returns meal with its own items slice, so callers can't change stored items
+func copySavedMeal(meal models.SavedMeal) *models.SavedMeal {
+	meal.Items = append([]models.SavedMealItem{}, meal.Items...)
+	return &meal
+}
+
+func (m *memory) GetSavedMeal(id int) (*models.SavedMeal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	meal, ok := m.savedMeals[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return copySavedMeal(meal), nil
+}
+
+func (m *memory) GetUsersSavedMeals(userID int) ([]models.SavedMeal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	meals := []models.SavedMeal{}
+	for _, meal := range m.savedMeals {
+		if meal.UserID == userID {
+			meals = append(meals, *copySavedMeal(meal))
+		}
+	}
+	sort.Slice(meals, func(i, j int) bool {
+		if meals[i].Name != meals[j].Name {
+			return meals[i].Name < meals[j].Name
+		}
+		return meals[i].ID < meals[j].ID
+	})
+	return meals, nil
+}
+
+func (m *memory) DeleteSavedMeal(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.savedMeals, id)
+	return nil
+}
+
+// LogSavedMeal validates every item as models.LogSavedMeal does before creating any entry
+func (m *memory) LogSavedMeal(meal models.SavedMeal, userID int, date time.Time, slot string) ([]models.Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	today := time.Now()
+	pending := []models.Entry{}
+	for _, item := range meal.Items {
+		entry := models.Entry{
+			UserID:    userID,
+			ProductID: item.ProductID,
+			PortionID: item.PortionID,
+			Quantity:  item.Quantity,
+			Date:      models.Day(date),
+			Slot:      slot,
+		}
+		err := m.checkEntry(entry, today)
+		if err != nil {
+			return nil, err
+		}
+		pending = append(pending, entry)
+	}
+	entries := []models.Entry{}
+	for _, entry := range pending {
+		entry.ID = m.id()
+		m.entries[entry.ID] = entry
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// checkEntry validates entry against its product, portion and users slots the same way models.CopyEntries does
+func (m *memory) checkEntry(entry models.Entry, today time.Time) error {
+	var product *models.Product
+	if found, ok := m.products[entry.ProductID]; ok {
+		product = &found
+	}
+	var portion *models.Portion
+	if found, ok := m.portions[entry.PortionID]; ok {
+		portion = &found
+	}
+	return models.ValidateEntry(entry, product, portion, m.usersSlots(entry.UserID), today)
+}
+
+// usersEntries returns users entries of the days between from and to (inclusive) ordered by date and id
+func (m *memory) usersEntries(userID int, from, to time.Time) []models.Entry {
+	entries := []models.Entry{}
+	for _, entry := range m.entries {
+		if entry.UserID != userID || entry.Date.Before(models.Day(from)) || entry.Date.After(models.Day(to)) {
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if !entries[i].Date.Equal(entries[j].Date) {
+			return entries[i].Date.Before(entries[j].Date)
+		}
+		return entries[i].ID < entries[j].ID
+	})
+	return entries
+}
+
+func (m *memory) GetUsersEntries(userID int, from, to time.Time, pagination *models.Pagination) (*[]models.Entry, *models.Pagination, error) {
+	pg := models.Pagination{}
+	if pagination != nil {
+		pg = *pagination
+	}
+	var lastDate time.Time
+	var lastID int
+	keyset, err := pg.CursorKeys(&lastDate, &lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	matched := m.usersEntries(userID, from, to)
+	if pagination == nil {
+		return &matched, nil, nil
+	}
+	start := pg.Offset()
+	if keyset {
+		start = sort.Search(len(matched), func(i int) bool {
+			return matched[i].Date.After(lastDate) || (matched[i].Date.Equal(lastDate) && matched[i].ID > lastID)
+		})
+	}
+	bounds, more := page(len(matched), start, pg.Limit())
+	out := append([]models.Entry{}, matched[bounds[0]:bounds[1]]...)
+	if !more {
+		return &out, pg.Result(len(matched), false), nil
+	}
+	last := out[len(out)-1]
+	return &out, pg.Result(len(matched), true, last.Date, last.ID), nil
+}
+
+func (m *memory) GetUsersEntryDates(userID int) (*[]time.Time, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seen := map[time.Time]bool{}
+	dates := []time.Time{}
+	for _, entry := range m.entries {
+		if entry.UserID != userID || seen[entry.Date] {
+			continue
+		}
+		seen[entry.Date] = true
+		dates = append(dates, entry.Date)
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+	return &dates, nil
+}
+
+// GetUsersDailySummaries sums the same nutrients models.GetUsersDailySummaries does, micronutrients are left out
+func (m *memory) GetUsersDailySummaries(userID int, from, to time.Time) ([]models.DaySummary, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	days := []models.DaySummary{}
+	for _, entry := range m.usersEntries(userID, from, to) {
+		portion := m.portions[entry.PortionID]
+		eaten := models.Nutrition{
+			Energy:       entry.Quantity * portion.Energy,
+			Protein:      entry.Quantity * portion.Protein,
+			Carbohydrate: entry.Quantity * portion.Carbohydrate,
+			Fat:          entry.Quantity * portion.Fat,
+			Fiber:        entry.Quantity * portion.Fiber,
+			Sugar:        entry.Quantity * portion.Sugar,
+			Sodium:       entry.Quantity * portion.Sodium,
+		}
+		if len(days) == 0 || !days[len(days)-1].Date.Equal(entry.Date) {
+			days = append(days, models.DaySummary{Date: entry.Date, Products: []models.ProductSummary{}})
+		}
+		day := &days[len(days)-1]
+		day.Total = day.Total.Add(eaten)
+		found := false
+		for i := range day.Products {
+			if day.Products[i].ProductID == entry.ProductID {
+				day.Products[i].Nutrition = day.Products[i].Nutrition.Add(eaten)
+				found = true
+			}
+		}
+		if !found {
+			name := m.products[entry.ProductID].Name
+			day.Products = append(day.Products, models.ProductSummary{ProductID: entry.ProductID, Name: name, Nutrition: eaten})
+		}
+	}
+	for _, day := range days {
+		products := day.Products
+		sort.Slice(products, func(i, j int) bool {
+			if products[i].Name != products[j].Name {
+				return products[i].Name < products[j].Name
+			}
+			return products[i].ProductID < products[j].ProductID
+		})
+	}
+	return days, nil
+}
+
+// CopyEntries checks every copy before creating any, so either all of them are created or none
+func (m *memory) CopyEntries(entries []models.Entry, dates []time.Time, dryRun bool) ([]models.Entry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	today := time.Now()
+	copies := []models.Entry{}
+	for _, date := range dates {
+		for _, entry := range entries {
+			entry.ID = 0
+			entry.Date = models.Day(date)
+			err := m.checkEntry(entry, today)
+			if err != nil {
+				return nil, err
+			}
+			copies = append(copies, entry)
+		}
+	}
+	if dryRun {
+		return copies, nil
+	}
+	for i := range copies {
+		copies[i].ID = m.id()
+		m.entries[copies[i].ID] = copies[i]
+	}
+	return copies, nil
+}
+
+// loggedProducts weights entries the same way models.GetUsersFrequentProducts does, 0.5^(age / half life)
+// over the last FrequentWindow days, usual portion and quantity is the pair with the highest weight
+func (m *memory) loggedProducts(userID int, today time.Time) []models.LoggedProduct {
+	type usage struct {
+		portionID int
+		quantity  float64
+	}
+	today = models.Day(today)
+	products := map[int]*models.LoggedProduct{}
+	lastIDs := map[int]int{}
+	usageWeights := map[int]map[usage]float64{}
+	usageIDs := map[int]map[usage]int{}
+	for _, entry := range m.entries {
+		age := today.Sub(entry.Date).Hours() / 24
+		product, ok := m.products[entry.ProductID]
+		if entry.UserID != userID || !ok || product.Deleted || age < 0 || age >= models.FrequentWindow {
+			continue
+		}
+		if _, ok := m.portions[entry.PortionID]; !ok {
+			continue
+		}
+		weight := math.Pow(0.5, age/models.FrequentHalfLife)
+		logged, ok := products[entry.ProductID]
+		if !ok {
+			logged = &models.LoggedProduct{ProductID: entry.ProductID}
+			products[entry.ProductID] = logged
+			usageWeights[entry.ProductID] = map[usage]float64{}
+			usageIDs[entry.ProductID] = map[usage]int{}
+		}
+		logged.Uses++
+		logged.Score += weight
+		if entry.Date.After(logged.LastLogged) {
+			logged.LastLogged = entry.Date
+		}
+		if entry.ID > lastIDs[entry.ProductID] {
+			lastIDs[entry.ProductID] = entry.ID
+		}
+		key := usage{entry.PortionID, entry.Quantity}
+		usageWeights[entry.ProductID][key] += weight
+		if entry.ID > usageIDs[entry.ProductID][key] {
+			usageIDs[entry.ProductID][key] = entry.ID
+		}
+	}
+	out := []models.LoggedProduct{}
+	for productID, logged := range products {
+		var best usage
+		bestWeight, bestID := -1.0, 0
+		for key, weight := range usageWeights[productID] {
+			id := usageIDs[productID][key]
+			if weight > bestWeight || (weight == bestWeight && id > bestID) {
+				best, bestWeight, bestID = key, weight, id
+			}
+		}
+		logged.PortionID = best.portionID
+		logged.Quantity = best.quantity
+		out = append(out, *logged)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if !out[i].LastLogged.Equal(out[j].LastLogged) {
+			return out[i].LastLogged.After(out[j].LastLogged)
+		}
+		return lastIDs[out[i].ProductID] > lastIDs[out[j].ProductID]
+	})
+	return out
+}
+
+func (m *memory) GetUsersRecentProducts(userID int, today time.Time, limit int) ([]models.LoggedProduct, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	products := m.loggedProducts(userID, today)
+	if len(products) > limit {
+		products = products[:limit]
+	}
+	return products, nil
+}
+
+func (m *memory) GetUsersFrequentProducts(userID int, today time.Time, limit int) ([]models.LoggedProduct, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	products := m.loggedProducts(userID, today)
+	sort.Slice(products, func(i, j int) bool {
+		if products[i].Score != products[j].Score {
+			return products[i].Score > products[j].Score
+		}
+		return products[i].ProductID > products[j].ProductID
+	})
+	if len(products) > limit {
+		products = products[:limit]
+	}
+	return products, nil
+}
+
+func (m *memory) AddFavourite(userID, productID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	product, ok := m.products[productID]
+	if !ok || product.Deleted {
+		return models.ErrNotFound
+	}
+	key := [2]int{userID, productID}
+	if _, ok := m.favourites[key]; !ok {
+		m.favourites[key] = time.Now()
+	}
+	return nil
+}
+
+func (m *memory) RemoveFavourite(userID, productID int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.favourites, [2]int{userID, productID})
+	return nil
+}
+
+func (m *memory) IsFavourite(userID, productID int) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	_, ok := m.favourites[[2]int{userID, productID}]
+	return ok, nil
+}
+
+func (m *memory) GetUsersFavourites(userID int, pagination models.Pagination) (*[]models.Product, *models.Pagination, error) {
+	var lastAdded time.Time
+	var lastID int
+	keyset, err := pagination.CursorKeys(&lastAdded, &lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	matched := []models.Product{}
+	for key := range m.favourites {
+		product, ok := m.products[key[1]]
+		if key[0] == userID && ok && !product.Deleted {
+			matched = append(matched, product)
+		}
+	}
+	added := func(product models.Product) time.Time {
+		return m.favourites[[2]int{userID, product.ID}]
+	}
+	before := func(product models.Product, at time.Time, id int) bool {
+		if !added(product).Equal(at) {
+			return added(product).Before(at)
+		}
+		return product.ID < id
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return before(matched[j], added(matched[i]), matched[i].ID)
+	})
+	start := pagination.Offset()
+	if keyset {
+		start = sort.Search(len(matched), func(i int) bool {
+			return before(matched[i], lastAdded, lastID)
+		})
+	}
+	bounds, more := page(len(matched), start, pagination.Limit())
+	out := append([]models.Product{}, matched[bounds[0]:bounds[1]]...)
+	if !more {
+		return &out, pagination.Result(len(matched), false), nil
+	}
+	last := out[len(out)-1]
+	return &out, pagination.Result(len(matched), true, added(last), last.ID), nil
+}
+
+// weightTaken mimics the unique constraint of one measurement per user and day
+func (m *memory) weightTaken(weight models.Weight, exceptID int) bool {
+	for id, existing := range m.weights {
+		if id != exceptID && existing.UserID == weight.UserID && existing.Date.Equal(models.Day(weight.Date)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *memory) CreateWeight(weight models.Weight) (*models.Weight, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.weightTaken(weight, 0) {
+		return nil, uniqueViolation("weights_user_id_date_key")
+	}
+	weight.ID = m.id()
+	weight.Date = models.Day(weight.Date)
+	m.weights[weight.ID] = weight
+	return &weight, nil
+}
+
+func (m *memory) GetWeight(id int) (*models.Weight, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	weight, ok := m.weights[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &weight, nil
+}
+
+func (m *memory) GetUsersWeights(userID int, from, to time.Time) ([]models.Weight, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	weights := []models.Weight{}
+	for _, weight := range m.weights {
+		if weight.UserID == userID && !weight.Date.Before(models.Day(from)) && !weight.Date.After(models.Day(to)) {
+			weights = append(weights, weight)
+		}
+	}
+	sort.Slice(weights, func(i, j int) bool {
+		return weights[i].Date.Before(weights[j].Date)
+	})
+	return weights, nil
+}
+
+func (m *memory) UpdateWeight(id int, new models.Weight) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	weight, ok := m.weights[id]
+	if !ok {
+		return nil
+	}
+	new.UserID = weight.UserID
+	if m.weightTaken(new, id) {
+		return uniqueViolation("weights_user_id_date_key")
+	}
+	weight.Date = models.Day(new.Date)
+	weight.Weight = new.Weight
+	weight.Unit = new.Unit
+	m.weights[id] = weight
+	return nil
+}
+
+func (m *memory) DeleteWeight(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.weights, id)
+	return nil
+}
+
+// SetGoal replaces users goal with the same weekday and effective date as models.SetGoal does
+func (m *memory) SetGoal(goal models.Goal) (*models.Goal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	goal.EffectiveFrom = models.Day(goal.EffectiveFrom)
+	for id, existing := range m.goals {
+		sameWeekday := (existing.Weekday == nil && goal.Weekday == nil) ||
+			(existing.Weekday != nil && goal.Weekday != nil && *existing.Weekday == *goal.Weekday)
+		if existing.UserID == goal.UserID && sameWeekday && existing.EffectiveFrom.Equal(goal.EffectiveFrom) {
+			delete(m.goals, id)
+		}
+	}
+	if goal.Weekday != nil {
+		weekday := *goal.Weekday
+		goal.Weekday = &weekday
+	}
+	goal.ID = m.id()
+	m.goals[goal.ID] = goal
+	return &goal, nil
+}
+
+func (m *memory) GetGoal(id int) (*models.Goal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	goal, ok := m.goals[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &goal, nil
+}
+
+func (m *memory) GetUsersGoals(userID int) ([]models.Goal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	goals := []models.Goal{}
+	for _, goal := range m.goals {
+		if goal.UserID == userID {
+			goals = append(goals, goal)
+		}
+	}
+	sort.Slice(goals, func(i, j int) bool {
+		if !goals[i].EffectiveFrom.Equal(goals[j].EffectiveFrom) {
+			return goals[i].EffectiveFrom.Before(goals[j].EffectiveFrom)
+		}
+		if goals[i].Weekday == nil || goals[j].Weekday == nil {
+			return goals[i].Weekday == nil && goals[j].Weekday != nil
+		}
+		return *goals[i].Weekday < *goals[j].Weekday
+	})
+	return goals, nil
+}
+
+func (m *memory) DeleteGoal(id int) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.goals, id)
+	return nil
+}
+
+func (m *memory) CreateProposal(proposal models.Proposal) (*models.Proposal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if proposal.Name != nil {
+		name := strings.ToLower(strings.TrimSpace(*proposal.Name))
+		proposal.Name = &name
+	}
+	if proposal.Portions == nil {
+		proposal.Portions = models.ProposedPortions{}
+	}
+	proposal.ID = m.id()
+	proposal.Status = models.ProposalPending
+	proposal.ModeratorID = 0
+	proposal.ReviewNote = ""
+	proposal.CreatedAt = time.Now()
+	proposal.ReviewedAt = nil
+	m.proposals[proposal.ID] = proposal
+	return &proposal, nil
+}
+
+func (m *memory) GetProposal(id int) (*models.Proposal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	proposal, ok := m.proposals[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	return &proposal, nil
+}
+
+func (m *memory) GetPendingProposals(pagination models.Pagination) (*[]models.Proposal, *models.Pagination, error) {
+	var lastCreated time.Time
+	var lastID int
+	keyset, err := pagination.CursorKeys(&lastCreated, &lastID)
+	if err != nil {
+		return nil, nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	matched := []models.Proposal{}
+	for _, proposal := range m.proposals {
+		if proposal.Status == models.ProposalPending {
+			matched = append(matched, proposal)
+		}
+	}
+	after := func(a models.Proposal, created time.Time, id int) bool {
+		if !a.CreatedAt.Equal(created) {
+			return a.CreatedAt.After(created)
+		}
+		return a.ID > id
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		return after(matched[j], matched[i].CreatedAt, matched[i].ID)
+	})
+	start := pagination.Offset()
+	if keyset {
+		start = sort.Search(len(matched), func(i int) bool {
+			return after(matched[i], lastCreated, lastID)
+		})
+	}
+	bounds, more := page(len(matched), start, pagination.Limit())
+	out := append([]models.Proposal{}, matched[bounds[0]:bounds[1]]...)
+	if !more {
+		return &out, pagination.Result(len(matched), false), nil
+	}
+	last := out[len(out)-1]
+	return &out, pagination.Result(len(matched), true, last.CreatedAt, last.ID), nil
+}
+
+func (m *memory) pendingProposal(id int) (*models.Proposal, error) {
+	proposal, ok := m.proposals[id]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	if proposal.Status != models.ProposalPending {
+		return nil, errors.New("Proposal is already reviewed")
+	}
+	return &proposal, nil
+}
+
+func (m *memory) reviewProposal(proposal models.Proposal, moderatorID int, status models.ProposalStatus, note string) *models.Proposal {
+	now := time.Now()
+	proposal.Status = status
+	proposal.ModeratorID = moderatorID
+	proposal.ReviewNote = note
+	proposal.ReviewedAt = &now
+	m.proposals[proposal.ID] = proposal
+	return &proposal
+}
+
+// ApproveProposal checks the whole proposal before changing anything, so it is applied entirely or not at all
+func (m *memory) ApproveProposal(id, moderatorID int, note string) (*models.Proposal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	proposal, err := m.pendingProposal(id)
+	if err != nil {
+		return nil, err
+	}
+	product, ok := m.products[proposal.ProductID]
+	if !ok {
+		return nil, models.ErrNotFound
+	}
+	if proposal.Name != nil {
+		product.Name, err = m.claimProductName(*proposal.Name, product.ID)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if proposal.Description != nil {
+		product.Description = *proposal.Description
+	}
+	for _, portion := range proposal.Portions {
+		existing, ok := m.portions[portion.ID]
+		if portion.ID != 0 && (!ok || existing.ProductID != product.ID) {
+			return nil, errors.Wrap(models.ErrNotFound, "While updating proposed portion")
+		}
+	}
+	m.products[product.ID] = product
+	for _, portion := range proposal.Portions {
+		portion = portion.WithBase()
+		portion.ProductID = product.ID
+		if portion.ID == 0 {
+			portion.ID = m.id()
+		}
+		m.portions[portion.ID] = portion
+	}
+	if len(proposal.Portions) > 0 {
+		m.refreshRecipesUsingProduct(product.ID, map[int]bool{})
+	}
+	return m.reviewProposal(*proposal, moderatorID, models.ProposalApproved, note), nil
+}
+
+func (m *memory) RejectProposal(id, moderatorID int, note string) (*models.Proposal, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	proposal, err := m.pendingProposal(id)
+	if err != nil {
+		return nil, err
+	}
+	return m.reviewProposal(*proposal, moderatorID, models.ProposalRejected, note), nil
+}
+
+func (m *memory) FindDuplicateClusters(minSimilarity float64, limit int) ([]models.DuplicateCluster, error) {
+	return nil, ErrNotSupported
+}
+
+func (m *memory) MergeProducts(survivorID int, duplicateIDs []int, userID int) (*models.Revision, error) {
+	return nil, ErrNotSupported
+}
+
+func (m *memory) GetRevision(id int) (*models.Revision, error) {
+	return nil, ErrNotSupported
+}
+
+func (m *memory) GetProductsRevisions(productID int, pagination models.Pagination) (*[]models.Revision, *models.Pagination, error) {
+	return nil, nil, ErrNotSupported
+}
+
+func (m *memory) RollbackProduct(revisionID, userID int) (*models.Revision, []models.Portion, error) {
+	return nil, nil, ErrNotSupported
+}
@@ -0,0 +1,32 @@
+package store
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWilsonScore(t *testing.T) {
+	cases := []struct {
+		name  string
+		up    int
+		down  int
+		score float64
+	}{
+		{"no votes", 0, 0, 0},
+		{"single up vote", 1, 0, 0.206543},
+		{"single down vote", 0, 1, 0},
+		{"even votes", 5, 5, 0.23659},
+		{"few up votes", 10, 0, 0.72246},
+		{"many up votes", 100, 0, 0.963005},
+		{"mostly up votes", 90, 10, 0.825633},
+	}
+	for _, c := range cases {
+		score := wilsonScore(c.up, c.down)
+		if math.Abs(score-c.score) > 1e-6 {
+			t.Errorf("%s: expected %v, got %v", c.name, c.score, score)
+		}
+	}
+	if wilsonScore(10, 0) <= wilsonScore(1, 0) {
+		t.Errorf("Expected more up votes to rank higher")
+	}
+}
@@ -0,0 +1,309 @@
+package store
+
+import (
+	"app/service/auth"
+	"app/service/models"
+	"database/sql"
+	"time"
+)
+
+// postgres implements every store with models functions
+type postgres struct {
+	db *sql.DB
+}
+
+// NewPostgres returns stores backed by the database
+func NewPostgres(db *sql.DB) *Stores {
+	pg := &postgres{db: db}
+	return &Stores{
+		Accounts:   pg,
+		Products:   pg,
+		Portions:   pg,
+		Entries:    pg,
+		Votes:      pg,
+		Recipes:    pg,
+		SavedMeals: pg,
+		Favourites: pg,
+		Weights:    pg,
+		Goals:      pg,
+		Proposals:  pg,
+		Duplicates: pg,
+		Revisions:  pg,
+	}
+}
+
+func (pg *postgres) CreateAccount(acc *models.Account) error {
+	return models.CreateAccount(pg.db, acc)
+}
+
+func (pg *postgres) VerifyAccount(acc *models.Account) error {
+	return models.VerifyAccount(pg.db, acc)
+}
+
+func (pg *postgres) GetAccountById(id int) (*models.Account, error) {
+	return models.GetAccountById(pg.db, id)
+}
+
+func (pg *postgres) GetAccountByEmail(email string) (*models.Account, error) {
+	return models.GetAccountByEmail(pg.db, email)
+}
+
+func (pg *postgres) GetAccountsCount() (int, error) {
+	return models.GetAccountsCount(pg.db)
+}
+
+func (pg *postgres) SetAccessLevel(id int, accessLevel auth.AccessLevel) error {
+	return models.SetAccessLevel(pg.db, id, accessLevel)
+}
+
+func (pg *postgres) SearchAccounts(email string, pagination models.Pagination) (*[]models.Account, *models.Pagination, error) {
+	return models.SearchAccounts(pg.db, email, pagination)
+}
+
+func (pg *postgres) ChangePasswordRequest(email string) error {
+	return models.ChangePasswordRequest(pg.db, email)
+}
+
+func (pg *postgres) ChangePassword(email, password string) error {
+	return models.ChangePassword(pg.db, email, password)
+}
+
+func (pg *postgres) CreateProductWithPortions(product models.Product, portions []models.Portion, barcodes []string) (*models.Product, []models.Portion, error) {
+	return models.CreateProductWithPortions(pg.db, product, portions, barcodes)
+}
+
+func (pg *postgres) GetProductById(id int) (*models.Product, error) {
+	return models.GetProductById(pg.db, id)
+}
+
+func (pg *postgres) GetProductByBarcode(code string) (*models.Product, error) {
+	return models.GetProductByBarcode(pg.db, code)
+}
+
+func (pg *postgres) GetProductsBarcodes(productID int) ([]string, error) {
+	return models.GetProductsBarcodes(pg.db, productID)
+}
+
+func (pg *postgres) BarcodeExists(code string) (bool, error) {
+	return models.BarcodeExists(pg.db, code)
+}
+
+func (pg *postgres) GetProductsByName(name string, userID int, favouritesOnly bool, pagination models.Pagination) (*[]models.ScoredProduct, *models.Pagination, error) {
+	return models.GetProductsByName(pg.db, name, userID, favouritesOnly, pagination)
+}
+
+func (pg *postgres) GetProductsByCreatorID(id int, pagination models.Pagination) (*[]models.Product, *models.Pagination, error) {
+	return models.GetProductsByCreatorID(pg.db, id, pagination)
+}
+
+func (pg *postgres) IsFavourite(userID, productID int) (bool, error) {
+	return models.IsFavourite(pg.db, userID, productID)
+}
+
+func (pg *postgres) UpdateProduct(id int, new models.Product, userID int) (*models.Product, error) {
+	return models.UpdateProduct(pg.db, id, new, userID)
+}
+
+func (pg *postgres) DeleteProduct(id, userID int) error {
+	return models.DeleteProduct(pg.db, id, userID)
+}
+
+func (pg *postgres) RestoreProduct(id int) error {
+	return models.RestoreProduct(pg.db, id)
+}
+
+func (pg *postgres) GetPortion(id int) (*models.Portion, error) {
+	return models.GetPortion(pg.db, id)
+}
+
+func (pg *postgres) GetProductsPortions(productID int) ([]models.Portion, error) {
+	return models.GetProductsPortions(pg.db, productID)
+}
+
+func (pg *postgres) AddProductPortion(portion models.Portion, userID int) (*models.Portion, error) {
+	return models.AddProductPortion(pg.db, portion, userID)
+}
+
+func (pg *postgres) EditProductPortion(portion models.Portion, userID int) (*models.Portion, error) {
+	return models.EditProductPortion(pg.db, portion, userID)
+}
+
+func (pg *postgres) RemoveProductPortion(productID, portionID, userID int) error {
+	return models.RemoveProductPortion(pg.db, productID, portionID, userID)
+}
+
+func (pg *postgres) CreateEntry(entry *models.Entry) (*models.Entry, error) {
+	return models.CreateEntry(pg.db, entry)
+}
+
+func (pg *postgres) GetEntry(id int) (*models.Entry, error) {
+	return models.GetEntry(pg.db, id)
+}
+
+func (pg *postgres) UpdateEntry(id int, new *models.Entry) error {
+	return models.UpdateEntry(pg.db, id, new)
+}
+
+func (pg *postgres) DeleteEntry(id int) error {
+	return models.DeleteEntry(pg.db, id)
+}
+
+func (pg *postgres) GetUsersSlots(userID int) ([]string, error) {
+	return models.GetUsersSlots(pg.db, userID)
+}
+
+func (pg *postgres) RateProduct(userID, productID int, vote models.Vote) error {
+	return models.RateProduct(pg.db, userID, productID, vote)
+}
+
+func (pg *postgres) GetProductVoteSummary(productID, userID int) (*models.VoteSummary, error) {
+	return models.GetProductVoteSummary(pg.db, productID, userID)
+}
+
+func (pg *postgres) CreateRecipe(recipe models.Product, ingredients []models.Ingredient) (*models.Product, []models.Ingredient, error) {
+	return models.CreateRecipe(pg.db, recipe, ingredients)
+}
+
+func (pg *postgres) GetRecipesIngredients(recipeID int) ([]models.Ingredient, error) {
+	return models.GetRecipesIngredients(pg.db, recipeID)
+}
+
+func (pg *postgres) CreateSavedMeal(meal models.SavedMeal) (*models.SavedMeal, error) {
+	return models.CreateSavedMeal(pg.db, meal)
+}
+
+func (pg *postgres) GetSavedMeal(id int) (*models.SavedMeal, error) {
+	return models.GetSavedMeal(pg.db, id)
+}
+
+func (pg *postgres) GetUsersSavedMeals(userID int) ([]models.SavedMeal, error) {
+	return models.GetUsersSavedMeals(pg.db, userID)
+}
+
+func (pg *postgres) DeleteSavedMeal(id int) error {
+	return models.DeleteSavedMeal(pg.db, id)
+}
+
+func (pg *postgres) LogSavedMeal(meal models.SavedMeal, userID int, date time.Time, slot string) ([]models.Entry, error) {
+	return models.LogSavedMeal(pg.db, meal, userID, date, slot)
+}
+
+func (pg *postgres) ProductNameExists(name string) (bool, error) {
+	return models.ProductNameExists(pg.db, name)
+}
+
+func (pg *postgres) GetUsersEntries(userID int, from, to time.Time, pagination *models.Pagination) (*[]models.Entry, *models.Pagination, error) {
+	return models.GetUsersEntries(pg.db, userID, from, to, pagination)
+}
+
+func (pg *postgres) GetUsersEntryDates(userID int) (*[]time.Time, error) {
+	return models.GetUsersEntryDates(pg.db, userID)
+}
+
+func (pg *postgres) GetUsersDailySummaries(userID int, from, to time.Time) ([]models.DaySummary, error) {
+	return models.GetUsersDailySummaries(pg.db, userID, from, to)
+}
+
+func (pg *postgres) CopyEntries(entries []models.Entry, dates []time.Time, dryRun bool) ([]models.Entry, error) {
+	return models.CopyEntries(pg.db, entries, dates, dryRun)
+}
+
+func (pg *postgres) GetUsersRecentProducts(userID int, today time.Time, limit int) ([]models.LoggedProduct, error) {
+	return models.GetUsersRecentProducts(pg.db, userID, today, limit)
+}
+
+func (pg *postgres) GetUsersFrequentProducts(userID int, today time.Time, limit int) ([]models.LoggedProduct, error) {
+	return models.GetUsersFrequentProducts(pg.db, userID, today, limit)
+}
+
+func (pg *postgres) SetUsersSlots(userID int, slots []string) error {
+	return models.SetUsersSlots(pg.db, userID, slots)
+}
+
+func (pg *postgres) AddFavourite(userID, productID int) error {
+	return models.AddFavourite(pg.db, userID, productID)
+}
+
+func (pg *postgres) RemoveFavourite(userID, productID int) error {
+	return models.RemoveFavourite(pg.db, userID, productID)
+}
+
+func (pg *postgres) GetUsersFavourites(userID int, pagination models.Pagination) (*[]models.Product, *models.Pagination, error) {
+	return models.GetUsersFavourites(pg.db, userID, pagination)
+}
+
+func (pg *postgres) CreateWeight(weight models.Weight) (*models.Weight, error) {
+	return models.CreateWeight(pg.db, weight)
+}
+
+func (pg *postgres) GetWeight(id int) (*models.Weight, error) {
+	return models.GetWeight(pg.db, id)
+}
+
+func (pg *postgres) GetUsersWeights(userID int, from, to time.Time) ([]models.Weight, error) {
+	return models.GetUsersWeights(pg.db, userID, from, to)
+}
+
+func (pg *postgres) UpdateWeight(id int, new models.Weight) error {
+	return models.UpdateWeight(pg.db, id, new)
+}
+
+func (pg *postgres) DeleteWeight(id int) error {
+	return models.DeleteWeight(pg.db, id)
+}
+
+func (pg *postgres) SetGoal(goal models.Goal) (*models.Goal, error) {
+	return models.SetGoal(pg.db, goal)
+}
+
+func (pg *postgres) GetGoal(id int) (*models.Goal, error) {
+	return models.GetGoal(pg.db, id)
+}
+
+func (pg *postgres) GetUsersGoals(userID int) ([]models.Goal, error) {
+	return models.GetUsersGoals(pg.db, userID)
+}
+
+func (pg *postgres) DeleteGoal(id int) error {
+	return models.DeleteGoal(pg.db, id)
+}
+
+func (pg *postgres) CreateProposal(proposal models.Proposal) (*models.Proposal, error) {
+	return models.CreateProposal(pg.db, proposal)
+}
+
+func (pg *postgres) GetProposal(id int) (*models.Proposal, error) {
+	return models.GetProposal(pg.db, id)
+}
+
+func (pg *postgres) GetPendingProposals(pagination models.Pagination) (*[]models.Proposal, *models.Pagination, error) {
+	return models.GetPendingProposals(pg.db, pagination)
+}
+
+func (pg *postgres) ApproveProposal(id, moderatorID int, note string) (*models.Proposal, error) {
+	return models.ApproveProposal(pg.db, id, moderatorID, note)
+}
+
+func (pg *postgres) RejectProposal(id, moderatorID int, note string) (*models.Proposal, error) {
+	return models.RejectProposal(pg.db, id, moderatorID, note)
+}
+
+func (pg *postgres) FindDuplicateClusters(minSimilarity float64, limit int) ([]models.DuplicateCluster, error) {
+	return models.FindDuplicateClusters(pg.db, minSimilarity, limit)
+}
+
+func (pg *postgres) MergeProducts(survivorID int, duplicateIDs []int, userID int) (*models.Revision, error) {
+	return models.MergeProducts(pg.db, survivorID, duplicateIDs, userID)
+}
+
+func (pg *postgres) GetRevision(id int) (*models.Revision, error) {
+	return models.GetRevision(pg.db, id)
+}
+
+func (pg *postgres) GetProductsRevisions(productID int, pagination models.Pagination) (*[]models.Revision, *models.Pagination, error) {
+	return models.GetProductsRevisions(pg.db, productID, pagination)
+}
+
+func (pg *postgres) RollbackProduct(revisionID, userID int) (*models.Revision, []models.Portion, error) {
+	return models.RollbackProduct(pg.db, revisionID, userID)
+}
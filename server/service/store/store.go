@@ -0,0 +1,150 @@
+package store
+
+import (
+	"app/service/auth"
+	"app/service/models"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ErrNotSupported is returned by stores which can't provide the operation, e.g. memory store has no trigram search
+var ErrNotSupported = errors.New("Operation is not supported by this store")
+
+// AccountStore keeps user accounts
+type AccountStore interface {
+	CreateAccount(acc *models.Account) error
+	VerifyAccount(acc *models.Account) error
+	GetAccountById(id int) (*models.Account, error)
+	GetAccountByEmail(email string) (*models.Account, error)
+	GetAccountsCount() (int, error)
+	SetAccessLevel(id int, accessLevel auth.AccessLevel) error
+	SearchAccounts(email string, pagination models.Pagination) (*[]models.Account, *models.Pagination, error)
+	ChangePasswordRequest(email string) error
+	ChangePassword(email, password string) error
+}
+
+// ProductStore keeps products together with their barcodes
+type ProductStore interface {
+	CreateProductWithPortions(product models.Product, portions []models.Portion, barcodes []string) (*models.Product, []models.Portion, error)
+	GetProductById(id int) (*models.Product, error)
+	GetProductByBarcode(code string) (*models.Product, error)
+	GetProductsBarcodes(productID int) ([]string, error)
+	BarcodeExists(code string) (bool, error)
+	GetProductsByName(name string, userID int, favouritesOnly bool, pagination models.Pagination) (*[]models.ScoredProduct, *models.Pagination, error)
+	GetProductsByCreatorID(id int, pagination models.Pagination) (*[]models.Product, *models.Pagination, error)
+	ProductNameExists(name string) (bool, error)
+	UpdateProduct(id int, new models.Product, userID int) (*models.Product, error)
+	DeleteProduct(id, userID int) error
+	RestoreProduct(id int) error
+}
+
+// PortionStore keeps portions of products
+type PortionStore interface {
+	GetPortion(id int) (*models.Portion, error)
+	GetProductsPortions(productID int) ([]models.Portion, error)
+	AddProductPortion(portion models.Portion, userID int) (*models.Portion, error)
+	EditProductPortion(portion models.Portion, userID int) (*models.Portion, error)
+	RemoveProductPortion(productID, portionID, userID int) error
+}
+
+// EntryStore keeps logged entries together with meal slots users log them into
+type EntryStore interface {
+	CreateEntry(entry *models.Entry) (*models.Entry, error)
+	GetEntry(id int) (*models.Entry, error)
+	UpdateEntry(id int, new *models.Entry) error
+	DeleteEntry(id int) error
+	GetUsersEntries(userID int, from, to time.Time, pagination *models.Pagination) (*[]models.Entry, *models.Pagination, error)
+	GetUsersEntryDates(userID int) (*[]time.Time, error)
+	GetUsersDailySummaries(userID int, from, to time.Time) ([]models.DaySummary, error)
+	CopyEntries(entries []models.Entry, dates []time.Time, dryRun bool) ([]models.Entry, error)
+	GetUsersRecentProducts(userID int, today time.Time, limit int) ([]models.LoggedProduct, error)
+	GetUsersFrequentProducts(userID int, today time.Time, limit int) ([]models.LoggedProduct, error)
+	GetUsersSlots(userID int) ([]string, error)
+	SetUsersSlots(userID int, slots []string) error
+}
+
+// FavouriteStore keeps products users marked as favourite
+type FavouriteStore interface {
+	AddFavourite(userID, productID int) error
+	RemoveFavourite(userID, productID int) error
+	IsFavourite(userID, productID int) (bool, error)
+	GetUsersFavourites(userID int, pagination models.Pagination) (*[]models.Product, *models.Pagination, error)
+}
+
+// WeightStore keeps users body weight measurements
+type WeightStore interface {
+	CreateWeight(weight models.Weight) (*models.Weight, error)
+	GetWeight(id int) (*models.Weight, error)
+	GetUsersWeights(userID int, from, to time.Time) ([]models.Weight, error)
+	UpdateWeight(id int, new models.Weight) error
+	DeleteWeight(id int) error
+}
+
+// GoalStore keeps users daily energy goals
+type GoalStore interface {
+	SetGoal(goal models.Goal) (*models.Goal, error)
+	GetGoal(id int) (*models.Goal, error)
+	GetUsersGoals(userID int) ([]models.Goal, error)
+	DeleteGoal(id int) error
+}
+
+// ProposalStore keeps product corrections suggested by users and applies approved ones
+type ProposalStore interface {
+	CreateProposal(proposal models.Proposal) (*models.Proposal, error)
+	GetProposal(id int) (*models.Proposal, error)
+	GetPendingProposals(pagination models.Pagination) (*[]models.Proposal, *models.Pagination, error)
+	ApproveProposal(id, moderatorID int, note string) (*models.Proposal, error)
+	RejectProposal(id, moderatorID int, note string) (*models.Proposal, error)
+}
+
+// DuplicateStore finds products with similar names and merges them
+type DuplicateStore interface {
+	FindDuplicateClusters(minSimilarity float64, limit int) ([]models.DuplicateCluster, error)
+	MergeProducts(survivorID int, duplicateIDs []int, userID int) (*models.Revision, error)
+}
+
+// RevisionStore keeps history of product changes and rolls products back to it
+type RevisionStore interface {
+	GetRevision(id int) (*models.Revision, error)
+	GetProductsRevisions(productID int, pagination models.Pagination) (*[]models.Revision, *models.Pagination, error)
+	RollbackProduct(revisionID, userID int) (*models.Revision, []models.Portion, error)
+}
+
+// VoteStore keeps users votes on products
+type VoteStore interface {
+	RateProduct(userID, productID int, vote models.Vote) error
+	GetProductVoteSummary(productID, userID int) (*models.VoteSummary, error)
+}
+
+// RecipeStore keeps recipes and their ingredients, portions of recipes are derived from the ingredients
+type RecipeStore interface {
+	CreateRecipe(recipe models.Product, ingredients []models.Ingredient) (*models.Product, []models.Ingredient, error)
+	GetRecipesIngredients(recipeID int) ([]models.Ingredient, error)
+}
+
+// SavedMealStore keeps users saved meals and logs them as entries
+type SavedMealStore interface {
+	CreateSavedMeal(meal models.SavedMeal) (*models.SavedMeal, error)
+	GetSavedMeal(id int) (*models.SavedMeal, error)
+	GetUsersSavedMeals(userID int) ([]models.SavedMeal, error)
+	DeleteSavedMeal(id int) error
+	LogSavedMeal(meal models.SavedMeal, userID int, date time.Time, slot string) ([]models.Entry, error)
+}
+
+// Stores bundles stores handlers read and write through
+type Stores struct {
+	Accounts   AccountStore
+	Products   ProductStore
+	Portions   PortionStore
+	Entries    EntryStore
+	Votes      VoteStore
+	Recipes    RecipeStore
+	SavedMeals SavedMealStore
+	Favourites FavouriteStore
+	Weights    WeightStore
+	Goals      GoalStore
+	Proposals  ProposalStore
+	Duplicates DuplicateStore
+	Revisions  RevisionStore
+}